@@ -1,11 +1,20 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/spf13/cobra"
+	cipher "webservices/packages/chiper"
+	c "webservices/packages/common"
+	"webservices/packages/storage"
+	"webservices/packages/structers"
 	"webservices/registry"
 )
 
@@ -27,14 +36,230 @@ func NewDBBackupCmd() *cobra.Command {
 				return
 			}
 
-			if err := registry.Database.Backup(db, output); err != nil {
+			format, _ := cmd.Flags().GetString("format")
+			sqlCopy, _ := cmd.Flags().GetBool("sql-copy")
+			batchSize, _ := cmd.Flags().GetInt("batch-size")
+			compress, _ := cmd.Flags().GetString("compress")
+			encrypt, _ := cmd.Flags().GetBool("encrypt")
+			incremental, _ := cmd.Flags().GetBool("incremental")
+			concurrency, _ := cmd.Flags().GetInt("concurrency")
+			tables, _ := cmd.Flags().GetString("tables")
+			include, _ := cmd.Flags().GetStringSlice("include")
+			exclude, _ := cmd.Flags().GetStringSlice("exclude")
+			filters, _ := cmd.Flags().GetStringArray("where")
+			profile, _ := cmd.Flags().GetString("profile")
+
+			where := make(map[string]string, len(filters))
+			for _, filter := range filters {
+				table, condition, ok := strings.Cut(filter, "=")
+				if !ok {
+					slog.Error("Invalid --where, expected table=condition", slog.String("where", filter))
+					return
+				}
+				where[strings.TrimSpace(table)] = condition
+			}
+
+			opts := structers.BackupOptions{
+				Output:      output,
+				Format:      format,
+				SQLCopy:     sqlCopy,
+				BatchSize:   batchSize,
+				Compress:    compress,
+				Encrypt:     encrypt,
+				Version:     GetVersion(),
+				Incremental: incremental,
+				Concurrency: concurrency,
+				Tables:      tables,
+				Include:     include,
+				Exclude:     exclude,
+				Where:       where,
+				Profile:     profile,
+			}
+
+			// remote runs are written to a temporary directory and uploaded once complete
+			var dest storage.Destination
+			if storage.IsRemote(output) {
+				if incremental {
+					slog.Error("Incremental backups need a local --output to read the previous run from")
+					return
+				}
+				if dest, err = storage.Open(output); err != nil {
+					slog.Error("Error opening backup destination", slog.Any("error", err))
+					return
+				}
+				if opts.Output, err = os.MkdirTemp("", "backup_"); err != nil {
+					slog.Error("Error creating temporary directory", slog.Any("error", err))
+					return
+				}
+				defer os.RemoveAll(opts.Output)
+			}
+
+			// also reads the previous encrypted run of an incremental backup, and seeds the masks
+			if encrypt || profile != "" || c.Env("KEY") != "" {
+				if opts.Key, err = appKey(); err != nil {
+					slog.Error("Error reading encryption key", slog.Any("error", err))
+					return
+				}
+			}
+
+			if err := registry.Database.Backup(db, opts); err != nil {
 				slog.Error("Backup failed", slog.Any("error", err))
+				return
+			}
+
+			if dest != nil {
+				uploaded, err := storage.Upload(context.Background(), dest, opts.Output, time.Now().Format("20060102"))
+				if err != nil {
+					slog.Error("Upload failed", slog.Any("error", err))
+					return
+				}
+
+				// retention only applies to local runs
+				slog.Info("✅ Backup uploaded successfully", slog.String("destination", dest.String()), slog.Int("files", len(uploaded)))
+				return
+			}
+
+			policy, err := retentionPolicy()
+			if err != nil {
+				slog.Error("Error reading retention policy", slog.Any("error", err))
+				return
+			}
+
+			pruned, err := structers.PruneBackups(pruneRoot(opts.Output), policy, opts.Key, false)
+			if err != nil {
+				slog.Error("Pruning old backups failed", slog.Any("error", err))
+				return
+			}
+			if len(pruned) > 0 {
+				slog.Info("Pruned old backups", slog.Int("runs", len(pruned)))
+			}
+		},
+	}
+
+	cmd.Flags().StringP("output", "o", defaultBackupOutput(), "Output directory for backup files, or a destination like s3://bucket/prefix or file:///mnt/backup")
+	cmd.Flags().StringP("format", "f", "json", "Output format: json, ndjson, csv, sql")
+	cmd.Flags().Bool("sql-copy", false, "Write COPY blocks instead of INSERT statements for the sql format")
+	cmd.Flags().Int("batch-size", structers.DefaultBackupBatchSize, "Number of rows read per query")
+	cmd.Flags().String("compress", "", "Bundle the backup into a single archive: gzip, zstd")
+	cmd.Flags().Bool("encrypt", false, "Encrypt the archive with the application KEY (gzip unless --compress is set)")
+	cmd.Flags().Bool("incremental", false, "Only export the rows changed since the previous backup")
+	cmd.Flags().IntP("concurrency", "c", 1, "Number of tables exported in parallel")
+	cmd.Flags().String("tables", structers.TablesRegistry, "Tables to back up: registry, models (registry and model tables) or schema (every table)")
+	cmd.Flags().StringSlice("include", nil, "Only back up tables matching these glob patterns, e.g. users,order_*")
+	cmd.Flags().StringSlice("exclude", nil, "Skip tables matching these glob patterns, e.g. audit_*")
+	cmd.Flags().String("profile", "", "Backup profile, anonymized masks the columns registered in Database.Masks")
+	cmd.Flags().StringArray("where", nil, "Filter the rows of a table, e.g. \"orders=created_at > now() - interval '1 year'\"")
+
+	return cmd
+}
+
+func NewDBBackupVerifyCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db:backup:verify",
+		Short: "Verify backup files against their manifest",
+		Run: func(cmd *cobra.Command, args []string) {
+			input, err := cmd.Flags().GetString("input")
+			if err != nil || input == "" {
+				slog.Error("Required flag --input not provided")
+				return
 			}
+
+			var key []byte
+			if c.Env("KEY") != "" {
+				if key, err = appKey(); err != nil {
+					slog.Error("Error reading encryption key", slog.Any("error", err))
+					return
+				}
+			}
+
+			input, cleanup, err := fetchBackup(input)
+			if err != nil {
+				slog.Error("Error downloading backup", slog.Any("error", err))
+				return
+			}
+			defer cleanup()
+
+			manifest, results, err := registry.Database.VerifyBackup(input, key)
+			if err != nil {
+				slog.Error("Verify failed", slog.Any("error", err))
+				return
+			}
+
+			fmt.Printf("Backup of %s (%s)\n", manifest.Timestamp.Format(time.RFC3339), manifest.Format)
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "TABLE\tROWS\tSTATUS\tDETAILS")
+
+			failed := 0
+			for i, v := range results {
+				rows := fmt.Sprint(manifest.Tables[i].Rows)
+				if v.Rows < 0 {
+					rows += " (unchecked)"
+				}
+
+				status := "OK"
+				if !v.OK() {
+					status = "Failed"
+					failed++
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", v.Table, rows, status, strings.Join(v.Problems, "; "))
+			}
+			w.Flush()
+
+			if failed > 0 {
+				slog.Error("Backup verification failed", slog.Int("tables", failed))
+				os.Exit(1)
+			}
+
+			slog.Info("✅ Backup verified successfully")
 		},
 	}
 
-	output := fmt.Sprintf("./storage/backup/%s", time.Now().Format("20060102"))
-	cmd.Flags().StringP("output", "o", output, "Output directory for backup files")
+	cmd.Flags().StringP("input", "i", "", "Backup directory or archive, local or like s3://bucket/prefix/20060102 (required)")
 
 	return cmd
 }
+
+func defaultBackupOutput() string {
+	return filepath.Join(backupRoot(), time.Now().Format("20060102"))
+}
+
+// backupRoot is the directory holding the dated backup folders, which retention applies to.
+func backupRoot() string {
+	return c.Env("BACKUP_PATH", "./storage/backup")
+}
+
+// pruneRoot is the root retention applies to after a backup into output, the parent of a dated
+// folder like the default output, otherwise output itself.
+func pruneRoot(output string) string {
+	if _, err := time.Parse("20060102", filepath.Base(output)); err == nil {
+		return filepath.Dir(output)
+	}
+	return output
+}
+
+// fetchBackup downloads a remote backup input to a temporary directory, local inputs are returned as is.
+func fetchBackup(input string) (string, func(), error) {
+	if !storage.IsRemote(input) {
+		return input, func() {}, nil
+	}
+
+	dir, err := os.MkdirTemp("", "restore_")
+	if err != nil {
+		return "", nil, err
+	}
+	cleanup := func() { os.RemoveAll(dir) }
+
+	slog.Info("Downloading backup", slog.String("input", input))
+	path, err := storage.Fetch(context.Background(), input, dir)
+	if err != nil {
+		cleanup()
+		return "", nil, err
+	}
+	return path, cleanup, nil
+}
+
+// appKey decodes the application KEY written by generate:key.
+func appKey() ([]byte, error) {
+	return cipher.DecodeKey(c.Env("KEY"))
+}
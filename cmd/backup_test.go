@@ -0,0 +1,76 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"webservices/packages/structers"
+)
+
+func TestBackupVerifyCmd(t *testing.T) {
+	t.Setenv("KEY", "")
+	dir := t.TempDir()
+
+	data := []byte(`[{"id": 1}, {"id": 2}]`)
+	file := "backup_users_20250101000000.json"
+	if err := os.WriteFile(filepath.Join(dir, file), data, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	checksum := sha256.Sum256(data)
+	manifest, _ := json.Marshal(structers.BackupManifest{
+		ID:        "20250101000000",
+		Timestamp: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Format:    "json",
+		Type:      structers.BackupFull,
+		Tables:    []structers.ManifestTable{{Table: "users", File: file, Rows: 2, SHA256: hex.EncodeToString(checksum[:])}},
+	})
+	if err := os.WriteFile(filepath.Join(dir, "backup_20250101000000.manifest.json"), manifest, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	output := captureStdout(t, func() {
+		cmd := NewDBBackupVerifyCmd()
+		cmd.SetArgs([]string{"--input", dir})
+		if err := cmd.Execute(); err != nil {
+			t.Fatal(err)
+		}
+	})
+
+	for _, want := range []string{"Backup of 2025-01-01T00:00:00Z (json)", "TABLE", "users", "OK"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output %q doesn't contain %q", output, want)
+		}
+	}
+}
+
+// helper
+
+// captureStdout returns what fn prints to stdout.
+func captureStdout(t *testing.T, fn func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	done := make(chan string)
+	go func() {
+		data, _ := io.ReadAll(r)
+		done <- string(data)
+	}()
+
+	fn()
+	w.Close()
+	return <-done
+}
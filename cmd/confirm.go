@@ -0,0 +1,103 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+	c "webservices/packages/common"
+	"webservices/packages/structers"
+	"webservices/registry"
+)
+
+// addDestructiveFlags registers the flags used by confirmDestructive.
+func addDestructiveFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("force", false, "Proceed without a terminal to confirm on, required to run in production")
+	cmd.Flags().Bool("backup", false, "Backup every table before proceeding, default in production")
+}
+
+// confirmDestructive guards commands that drop or wipe data.
+// On an interactive terminal it lists what will be affected and asks for confirmation and an optional backup,
+// without a terminal it refuses unless --force is given. In production --force is required as well,
+// and a backup is taken unless --backup=false.
+func confirmDestructive(cmd *cobra.Command, db *gorm.DB, action string, affected []string) bool {
+	force, _ := cmd.Flags().GetBool("force")
+	backup, _ := cmd.Flags().GetBool("backup")
+	production := c.Env("GO_ENV") == "production"
+
+	if production && !force {
+		slog.Error("Refusing to run in production without --force", slog.String("action", action))
+		return false
+	}
+	if production && !cmd.Flags().Changed("backup") {
+		backup = true
+	}
+
+	if isTerminal(os.Stdin) {
+		fmt.Printf("This will %s:\n", action)
+		for _, item := range affected {
+			fmt.Printf("  - %s\n", item)
+		}
+		if len(affected) == 0 {
+			fmt.Println("  (nothing)")
+		}
+
+		if !prompt("Do you want to continue? [y/N] ", false) {
+			slog.Info("Aborted")
+			return false
+		}
+
+		if !backup && !cmd.Flags().Changed("backup") {
+			backup = prompt("Create a backup before proceeding? [Y/n] ", true)
+		}
+	} else if !force {
+		slog.Error("Refusing to run without a terminal to confirm on, use --force", slog.String("action", action))
+		return false
+	}
+
+	if backup {
+		// every table of the schema, the registry may list none of the tables about to be destroyed
+		opts := structers.BackupOptions{Output: defaultBackupOutput(), Version: GetVersion(), Tables: structers.TablesSchema}
+		if err := safetyBackup(db, opts); err != nil {
+			slog.Error("Backup failed, aborting", slog.Any("error", err))
+			return false
+		}
+	}
+
+	return true
+}
+
+// safetyBackup takes the backup of confirmDestructive.
+var safetyBackup = func(db *gorm.DB, opts structers.BackupOptions) error {
+	return registry.Database.Backup(db, opts)
+}
+
+func prompt(question string, fallback bool) bool {
+	fmt.Print(question)
+
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(strings.TrimSpace(answer)) {
+	case "y", "yes":
+		return true
+	case "n", "no":
+		return false
+	default:
+		return fallback
+	}
+}
+
+func isTerminal(f *os.File) bool {
+	stat, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return stat.Mode()&os.ModeCharDevice != 0
+}
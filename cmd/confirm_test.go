@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/spf13/cobra"
+	"gorm.io/gorm"
+	"webservices/packages/structers"
+)
+
+func TestConfirmDestructiveBacksUpFirst(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		backupErr error
+		want      []string
+	}{
+		{name: "backup then destroy", args: []string{"--force"}, want: []string{"backup", "destroy"}},
+		{name: "failed backup aborts", args: []string{"--force"}, backupErr: errors.New("disk full"), want: []string{"backup"}},
+		{name: "backup skipped", args: []string{"--force", "--backup=false"}, want: []string{"destroy"}},
+		{name: "refused without force", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			t.Setenv("GO_ENV", "production")
+			withoutTerminal(t)
+
+			var steps []string
+			var tables string
+			stubSafetyBackup(t, func(db *gorm.DB, opts structers.BackupOptions) error {
+				steps = append(steps, "backup")
+				tables = opts.Tables
+				return tt.backupErr
+			})
+
+			cmd := &cobra.Command{}
+			addDestructiveFlags(cmd)
+			if err := cmd.ParseFlags(tt.args); err != nil {
+				t.Fatal(err)
+			}
+
+			if confirmDestructive(cmd, nil, "drop every table", []string{"users"}) {
+				steps = append(steps, "destroy")
+			}
+
+			if !reflect.DeepEqual(steps, tt.want) {
+				t.Errorf("steps = %v, want %v", steps, tt.want)
+			}
+			if tables != "" && tables != structers.TablesSchema {
+				t.Errorf("backup of the %q tables, want every table of the schema", tables)
+			}
+		})
+	}
+}
+
+// helper
+
+// withoutTerminal replaces stdin with a pipe for the duration of the test.
+func withoutTerminal(t *testing.T) {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = r
+	t.Cleanup(func() {
+		os.Stdin = stdin
+		r.Close()
+		w.Close()
+	})
+}
+
+func stubSafetyBackup(t *testing.T, backup func(db *gorm.DB, opts structers.BackupOptions) error) {
+	t.Helper()
+	original := safetyBackup
+	safetyBackup = backup
+	t.Cleanup(func() { safetyBackup = original })
+}
@@ -0,0 +1,95 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+
+	"github.com/spf13/cobra"
+	"webservices/packages/structers"
+	"webservices/registry"
+)
+
+func NewDBDiffCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db:diff",
+		Short: "Compare registered models with the database schema",
+		Run: func(cmd *cobra.Command, args []string) {
+			db, err := initDB(cmd)
+			if err != nil {
+				slog.Error("Initializing database: ", slog.Any("error", err))
+				return
+			}
+
+			diff, err := registry.Database.Diff(db)
+			if err != nil {
+				slog.Error("Schema diff failed", slog.Any("error", err))
+				return
+			}
+
+			if diff.Empty() {
+				fmt.Println("Schema is in sync with the models")
+				return
+			}
+
+			printDiff(diff)
+
+			migration, _ := cmd.Flags().GetBool("migration")
+			if !migration {
+				return
+			}
+
+			drop, _ := cmd.Flags().GetBool("drop")
+			up, down, err := registry.Database.Reconcile(db, diff, drop)
+			if err != nil {
+				slog.Error("Generating migration failed", slog.Any("error", err))
+				return
+			}
+
+			name, _ := cmd.Flags().GetString("name")
+			version, err := structers.WriteMigration(registry.Database.GetMigrations(), name, up, down)
+			if err != nil {
+				slog.Error("Writing migration failed", slog.Any("error", err))
+				return
+			}
+
+			fmt.Printf("created: %s_%s (review it before running migrate)\n", version, name)
+		},
+	}
+
+	cmd.Flags().Bool("migration", false, "Write a versioned migration that reconciles the differences")
+	cmd.Flags().StringP("name", "n", "reconcile_schema", "Name of the generated migration")
+	cmd.Flags().Bool("drop", false, "Also drop the columns, indexes and constraints the models don't have")
+
+	return cmd
+}
+
+func printDiff(diff *structers.SchemaDiff) {
+	for _, t := range diff.MissingTables {
+		fmt.Printf("+ table %s (missing in database)\n", t)
+	}
+	for _, t := range diff.ExtraTables {
+		fmt.Printf("- table %s (no model)\n", t)
+	}
+
+	for _, td := range diff.Tables {
+		fmt.Printf("~ table %s\n", td.Table)
+		printItems("+ column", td.MissingColumns, "missing in database")
+		printItems("- column", td.ExtraColumns, "not in model")
+		for _, c := range td.TypeMismatches {
+			fmt.Printf("    ~ column %s type: model %s, database %s\n", c.Column, c.Expected, c.Actual)
+		}
+		for _, c := range td.NullMismatches {
+			fmt.Printf("    ~ column %s nullability: model %s, database %s\n", c.Column, c.Expected, c.Actual)
+		}
+		printItems("+ index", td.MissingIndexes, "missing in database")
+		printItems("- index", td.ExtraIndexes, "not in model")
+		printItems("+ constraint", td.MissingConstraints, "missing in database")
+		printItems("- constraint", td.ExtraConstraints, "not in model")
+	}
+}
+
+func printItems(prefix string, items []string, note string) {
+	for _, item := range items {
+		fmt.Printf("    %s %s (%s)\n", prefix, item, note)
+	}
+}
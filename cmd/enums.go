@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"webservices/registry"
+)
+
+func NewDBEnumsCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db:enums",
+		Short: "Report drift between registered enums and the database",
+		Run: func(cmd *cobra.Command, args []string) {
+			db, err := initDB(cmd)
+			if err != nil {
+				slog.Error("Initializing database: ", slog.Any("error", err))
+				return
+			}
+
+			drifts, err := registry.Database.EnumDrift(db)
+			if err != nil {
+				slog.Error("Enum drift failed", slog.Any("error", err))
+				return
+			}
+
+			if len(drifts) == 0 {
+				fmt.Println("No enums found")
+				return
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "ENUM\tSTATUS\tDETAILS")
+			for _, d := range drifts {
+				var status string
+				var details []string
+
+				switch {
+				case !d.Registered:
+					status = "Unregistered"
+					details = append(details, "values: "+strings.Join(d.Labels, ", "))
+				case !d.Exists:
+					status = "Missing"
+					details = append(details, "create: "+strings.Join(d.Missing, ", "))
+				case d.InSync():
+					status = "OK"
+				default:
+					status = "Drift"
+					for _, r := range d.Renames {
+						details = append(details, fmt.Sprintf("rename: %s -> %s", r.From, r.To))
+					}
+					if len(d.Missing) > 0 {
+						details = append(details, "add: "+strings.Join(d.Missing, ", "))
+					}
+					if len(d.Extra) > 0 {
+						details = append(details, "remove: "+strings.Join(d.Extra, ", "))
+					}
+				}
+
+				fmt.Fprintf(w, "%s\t%s\t%s\n", d.Name, status, strings.Join(details, "; "))
+			}
+			w.Flush()
+		},
+	}
+
+	return cmd
+}
@@ -37,8 +37,14 @@ func NewMDBFactoryCmd() *cobra.Command {
 			timestamp := time.Now().Format("20060102150405")
 			filename := fmt.Sprintf("%s_%s_factory.go", timestamp, name)
 
+			model, _ := cmd.Flags().GetString("model")
+			if model == "" {
+				model = name
+			}
+
 			data := map[string]any{
-				"Name": c.ToUpper(c.ToCamelCase(name)),
+				"Name":  c.ToUpper(c.ToCamelCase(name)),
+				"Model": c.ToUpper(c.ToCamelCase(model)),
 			}
 
 			file.Create(filepath.Join(outputDir, filename), factoryCode, &data)
@@ -46,41 +52,37 @@ func NewMDBFactoryCmd() *cobra.Command {
 	}
 
 	cmd.Flags().StringP("name", "n", "", "Factory name (required)")
+	cmd.Flags().StringP("model", "m", "", "Model built by the factory, from ./src/model (default the factory name)")
 	cmd.Flags().StringP("output", "o", "./database/factory", "Output directory for factory files")
 
 	return cmd
 }
 
 const factoryCode = `package factory
+
 import (
+	"webservices/packages/factory"
+	"webservices/src/model"
+
 	"gorm.io/gorm"
 )
 
-type {{.Name}}Factory struct {
-	db *gorm.DB
-}
-
-func New{{.Name}}Factory(db *gorm.DB) *{{.Name}}Factory {
-	return &{{.Name}}Factory{db: db}
-}
-
-func (f *{{.Name}}Factory) Create() error {
-	// Implement your factory logic here
-	// Example:
-	// data := &models.{{.Name}}{
-	//     Field1: faker.Word(),
-	//     Field2: faker.Email(),
-	// }
-	// return f.db.Create(data).Error
-	return nil
-}
-
-func (f *{{.Name}}Factory) CreateBatch(count int) error {
-	for range count {
-		if err := f.Create(); err != nil {
-			return err
+// New{{.Name}}Factory builds {{.Model}} records, use it in a seeder at [registry/database.go]:
+//
+//	factory.New{{.Name}}Factory(db).State("example").CreateBatch(count)
+//	factory.New{{.Name}}Factory(db).For(parentFactory).Has(childFactory, 3).Create()
+func New{{.Name}}Factory(db *gorm.DB) *factory.Factory[model.{{.Model}}] {
+	return factory.Define(db, func(seq int) model.{{.Model}} {
+		return model.{{.Model}}{
+			// default attributes, seq numbers the records from 1, example:
+			// Name:  faker.Name(),
+			// Email: fmt.Sprintf("%d.%s", seq, faker.Email()),
+			// fields tagged like ` + "`" + `faker:"email"` + "`" + ` are filled when left empty
 		}
-	}
-	return nil
+	}).
+		DefineState("example", func(m *model.{{.Model}}) {
+			// attributes of the state, example:
+			// m.Role = "admin"
+		})
 }
 `
@@ -1,11 +1,20 @@
 package cmd
 
 import (
+	"fmt"
 	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
 	"gorm.io/gorm"
 	"webservices/database"
+	c "webservices/packages/common"
+	"webservices/packages/structers"
 	"webservices/registry"
 )
 
@@ -14,14 +23,60 @@ func NewMigrateCmd() *cobra.Command {
 		Use:   "migrate",
 		Short: "Run database migration",
 		Run: func(cmd *cobra.Command, args []string) {
-			slog.Info("Starting database migration...")
 			db, err := initDB(cmd)
 			if err != nil {
 				slog.Error("Initializing database: ", slog.Any("error", err))
 				return
 			}
 
+			registry.Database.LockTimeout, _ = cmd.Flags().GetDuration("lock-timeout")
 			fresh, _ := cmd.Flags().GetBool("fresh")
+			pretend, _ := cmd.Flags().GetBool("pretend")
+			registry.Database.DropEnumValues, _ = cmd.Flags().GetBool("drop-enum-values")
+
+			if pretend {
+				output, _ := cmd.Flags().GetString("output")
+				if err := pretendMigrate(db, fresh, output); err != nil {
+					slog.Error("Pretend migration failed", slog.Any("error", err))
+				}
+				return
+			}
+
+			if fresh {
+				tables, err := db.Migrator().GetTables()
+				if err != nil {
+					slog.Error("Listing tables", slog.Any("error", err))
+					return
+				}
+				for _, e := range registry.Database.GetEnums() {
+					tables = append(tables, "type "+e.Name)
+				}
+				if !confirmDestructive(cmd, db, "drop every table and enum", tables) {
+					return
+				}
+			}
+
+			if registry.Database.DropEnumValues && !fresh {
+				drifts, err := registry.Database.EnumDrift(db)
+				if err != nil {
+					slog.Error("Enum drift failed", slog.Any("error", err))
+					return
+				}
+
+				var values []string
+				for _, d := range drifts {
+					if !d.Registered {
+						continue
+					}
+					for _, v := range d.Extra {
+						values = append(values, d.Name+"."+v)
+					}
+				}
+				if len(values) > 0 && !confirmDestructive(cmd, db, "remove these enum values", values) {
+					return
+				}
+			}
+
 			if err := registry.Database.Migrate(db, fresh); err != nil {
 				slog.Error("Migration failed", slog.Any("error", err))
 			}
@@ -29,10 +84,193 @@ func NewMigrateCmd() *cobra.Command {
 	}
 
 	cmd.Flags().BoolP("fresh", "f", false, "force fresh migration")
+	cmd.Flags().Bool("drop-enum-values", false, "Remove enum values which are no longer registered, rewriting dependent columns")
+	cmd.Flags().Bool("pretend", false, "Print the SQL statements without changing the database")
+	cmd.Flags().StringP("output", "o", "", "Write the pretend SQL to a file instead of stdout")
+	cmd.Flags().Duration("lock-timeout", lockTimeout(), "How long to wait for another migration to finish")
+	addDestructiveFlags(cmd)
+
+	return cmd
+}
+
+func NewMigrateStatusCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate:status",
+		Short: "Show status of versioned migrations",
+		Run: func(cmd *cobra.Command, args []string) {
+			db, err := initDB(cmd)
+			if err != nil {
+				slog.Error("Initializing database: ", slog.Any("error", err))
+				return
+			}
+
+			statuses, err := registry.Database.MigrationStatus(db)
+			if err != nil {
+				slog.Error("Migration status failed", slog.Any("error", err))
+				return
+			}
+
+			if len(statuses) == 0 {
+				fmt.Println("No migrations found")
+				return
+			}
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "MIGRATION\tSTATUS\tBATCH\tAPPLIED AT")
+			for _, s := range statuses {
+				status, batch, appliedAt := "Pending", "-", "-"
+				if s.Applied {
+					status = "Applied"
+					if s.Missing {
+						status = "Applied (missing file)"
+					}
+					batch = fmt.Sprint(s.Batch)
+					appliedAt = s.AppliedAt.Format("2006-01-02 15:04:05")
+				}
+				fmt.Fprintf(w, "%s_%s\t%s\t%s\t%s\n", s.Version, s.Name, status, batch, appliedAt)
+			}
+			w.Flush()
+		},
+	}
+
+	return cmd
+}
+
+func NewMigrateRollbackCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate:rollback",
+		Short: "Rollback the last batch of migrations",
+		Run: func(cmd *cobra.Command, args []string) {
+			db, err := initDB(cmd)
+			if err != nil {
+				slog.Error("Initializing database: ", slog.Any("error", err))
+				return
+			}
+
+			step, _ := cmd.Flags().GetInt("step")
+			statuses, err := registry.Database.MigrationStatus(db)
+			if err != nil {
+				slog.Error("Migration status failed", slog.Any("error", err))
+				return
+			}
+			if !confirmDestructive(cmd, db, "rollback these migrations", rollbackTargets(statuses, step)) {
+				return
+			}
+
+			registry.Database.LockTimeout, _ = cmd.Flags().GetDuration("lock-timeout")
+			if err := registry.Database.Rollback(db, step); err != nil {
+				slog.Error("Rollback failed", slog.Any("error", err))
+			}
+		},
+	}
+
+	cmd.Flags().IntP("step", "s", 0, "Number of migrations to rollback, default is the last batch")
+	cmd.Flags().Duration("lock-timeout", lockTimeout(), "How long to wait for another migration to finish")
+	addDestructiveFlags(cmd)
 
 	return cmd
 }
 
+func NewMigrateResetCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate:reset",
+		Short: "Rollback all migrations",
+		Run: func(cmd *cobra.Command, args []string) {
+			db, err := initDB(cmd)
+			if err != nil {
+				slog.Error("Initializing database: ", slog.Any("error", err))
+				return
+			}
+
+			statuses, err := registry.Database.MigrationStatus(db)
+			if err != nil {
+				slog.Error("Migration status failed", slog.Any("error", err))
+				return
+			}
+
+			var applied []string
+			for _, s := range statuses {
+				if s.Applied {
+					applied = append(applied, s.Version+"_"+s.Name)
+				}
+			}
+			if !confirmDestructive(cmd, db, "rollback every applied migration", applied) {
+				return
+			}
+
+			registry.Database.LockTimeout, _ = cmd.Flags().GetDuration("lock-timeout")
+			if err := registry.Database.Reset(db); err != nil {
+				slog.Error("Reset failed", slog.Any("error", err))
+			}
+		},
+	}
+
+	cmd.Flags().Duration("lock-timeout", lockTimeout(), "How long to wait for another migration to finish")
+	addDestructiveFlags(cmd)
+
+	return cmd
+}
+
+// helper
+
+// rollbackTargets lists the applied migrations Rollback reverts, newest first.
+func rollbackTargets(statuses []structers.MigrationStatus, step int) []string {
+	var applied []structers.MigrationStatus
+	for _, s := range statuses {
+		if s.Applied {
+			applied = append(applied, s)
+		}
+	}
+	slices.SortFunc(applied, func(a, b structers.MigrationStatus) int {
+		if a.Batch != b.Batch {
+			return b.Batch - a.Batch
+		}
+		return strings.Compare(b.Version, a.Version)
+	})
+
+	var targets []string
+	for i, s := range applied {
+		if (step > 0 && i >= step) || (step <= 0 && s.Batch != applied[0].Batch) {
+			break
+		}
+		targets = append(targets, s.Version+"_"+s.Name)
+	}
+	return targets
+}
+
+func pretendMigrate(db *gorm.DB, fresh bool, output string) error {
+	statements, err := registry.Database.Pretend(db, fresh)
+	if err != nil {
+		return err
+	}
+
+	content := strings.Join(statements, "\n\n") + "\n"
+	if output == "" {
+		fmt.Print(content)
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(output), 0755); err != nil {
+		return err
+	}
+
+	if err := os.WriteFile(output, []byte(content), 0644); err != nil {
+		return err
+	}
+
+	slog.Info("Pretend SQL written", slog.String("path", output), slog.Int("statements", len(statements)))
+	return nil
+}
+
+// lockTimeout reads the default migration lock timeout from MIGRATION_LOCK_TIMEOUT (e.g. "30s", "2m")
+func lockTimeout() time.Duration {
+	timeout, err := time.ParseDuration(c.Env("MIGRATION_LOCK_TIMEOUT", "1m"))
+	if err != nil {
+		return structers.DefaultLockTimeout
+	}
+	return timeout
+}
+
 func initDB(cmd *cobra.Command) (*gorm.DB, error) {
 	dsn, err := cmd.Flags().GetString("dsn")
 	if err != nil {
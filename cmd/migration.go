@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/spf13/cobra"
+	"webservices/packages/file"
+	"webservices/registry"
+)
+
+func NewMakeMigrationCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "make:migration",
+		Short: "Create versioned migration",
+		Run: func(cmd *cobra.Command, args []string) {
+			name, err := cmd.Flags().GetString("name")
+			if err != nil || name == "" {
+				slog.Error("Required flag --name not provided")
+				return
+			}
+
+			outputDir, err := cmd.Flags().GetString("output")
+			if err != nil {
+				slog.Error("Getting output directory: %v", slog.Any("error", err))
+				return
+			}
+
+			if err := os.MkdirAll(outputDir, 0755); err != nil {
+				slog.Error("Creating output directory", slog.Any("error", err))
+				return
+			}
+
+			timestamp := time.Now().Format("20060102150405")
+			name = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(name), "-", "_"))
+
+			data := map[string]any{
+				"Name":    name,
+				"Version": timestamp,
+			}
+
+			file.Create(filepath.Join(outputDir, fmt.Sprintf("%s_%s.up.sql", timestamp, name)), migrationUpCode, &data)
+			file.Create(filepath.Join(outputDir, fmt.Sprintf("%s_%s.down.sql", timestamp, name)), migrationDownCode, &data)
+		},
+	}
+
+	cmd.Flags().StringP("name", "n", "", "Migration name (required)")
+	cmd.Flags().StringP("output", "o", registry.Database.GetMigrations(), "Output directory for migration files")
+
+	return cmd
+}
+
+const migrationUpCode = `-- migration: {{.Version}}_{{.Name}} (up)
+-- write the statements to apply this migration, example:
+-- ALTER TABLE users RENAME COLUMN name TO full_name;
+`
+
+const migrationDownCode = `-- migration: {{.Version}}_{{.Name}} (down)
+-- write the statements to revert this migration, example:
+-- ALTER TABLE users RENAME COLUMN full_name TO name;
+`
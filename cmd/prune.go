@@ -0,0 +1,108 @@
+package cmd
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+
+	"github.com/spf13/cobra"
+	c "webservices/packages/common"
+	"webservices/packages/structers"
+)
+
+func NewDBBackupPruneCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db:backup:prune",
+		Short: "Delete backups outside of the retention policy",
+		Run: func(cmd *cobra.Command, args []string) {
+			policy, err := retentionPolicy()
+			if err != nil {
+				slog.Error("Error reading retention policy", slog.Any("error", err))
+				return
+			}
+
+			for flag, value := range map[string]*int{
+				"keep-last":    &policy.Last,
+				"keep-daily":   &policy.Daily,
+				"keep-weekly":  &policy.Weekly,
+				"keep-monthly": &policy.Monthly,
+			} {
+				if cmd.Flags().Changed(flag) {
+					*value, _ = cmd.Flags().GetInt(flag)
+				}
+			}
+
+			if !policy.Enabled() {
+				slog.Info("No retention policy configured, set BACKUP_KEEP_* to prune backups")
+				return
+			}
+
+			dir, _ := cmd.Flags().GetString("dir")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+
+			// reads the manifests of encrypted archives to keep the runs incrementals are based on
+			var key []byte
+			if c.Env("KEY") != "" {
+				if key, err = appKey(); err != nil {
+					slog.Error("Error reading encryption key", slog.Any("error", err))
+					return
+				}
+			}
+
+			pruned, err := structers.PruneBackups(dir, policy, key, dryRun)
+			if err != nil {
+				slog.Error("Prune failed", slog.Any("error", err))
+				return
+			}
+
+			if len(pruned) == 0 {
+				fmt.Println("Nothing to prune")
+				return
+			}
+
+			action := "Deleted"
+			if dryRun {
+				action = "Would delete"
+			}
+			for _, run := range pruned {
+				fmt.Printf("%s backup of %s:\n", action, run.Timestamp.Format("2006-01-02 15:04"))
+				for _, file := range run.Files {
+					fmt.Printf("  - %s\n", file)
+				}
+			}
+
+			if !dryRun {
+				slog.Info("✅ Backups pruned successfully", slog.Int("runs", len(pruned)))
+			}
+		},
+	}
+
+	cmd.Flags().String("dir", backupRoot(), "Backup root directory")
+	cmd.Flags().Bool("dry-run", false, "List the backups that would be deleted without deleting them")
+	cmd.Flags().Int("keep-last", 0, "Keep the last N backups (default BACKUP_KEEP_LAST)")
+	cmd.Flags().Int("keep-daily", 0, "Keep one backup for each of the last N days (default BACKUP_KEEP_DAILY)")
+	cmd.Flags().Int("keep-weekly", 0, "Keep one backup for each of the last N weeks (default BACKUP_KEEP_WEEKLY)")
+	cmd.Flags().Int("keep-monthly", 0, "Keep one backup for each of the last N months (default BACKUP_KEEP_MONTHLY)")
+
+	return cmd
+}
+
+// retentionPolicy reads the BACKUP_KEEP_* variables, unset tiers are disabled.
+func retentionPolicy() (structers.RetentionPolicy, error) {
+	var policy structers.RetentionPolicy
+
+	for key, value := range map[string]*int{
+		"BACKUP_KEEP_LAST":    &policy.Last,
+		"BACKUP_KEEP_DAILY":   &policy.Daily,
+		"BACKUP_KEEP_WEEKLY":  &policy.Weekly,
+		"BACKUP_KEEP_MONTHLY": &policy.Monthly,
+	} {
+		n, err := strconv.Atoi(c.Env(key, "0"))
+		if err != nil || n < 0 {
+			return policy, fmt.Errorf("invalid %s: %q", key, c.Env(key))
+		}
+		*value = n
+	}
+
+	return policy, nil
+}
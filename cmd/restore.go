@@ -0,0 +1,71 @@
+package cmd
+
+import (
+	"log/slog"
+
+	"github.com/spf13/cobra"
+	c "webservices/packages/common"
+	"webservices/packages/structers"
+	"webservices/registry"
+)
+
+func NewDBRestoreCmd() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "db:restore",
+		Short: "Restore database from backup files",
+		Run: func(cmd *cobra.Command, args []string) {
+			input, err := cmd.Flags().GetString("input")
+			if err != nil || input == "" {
+				slog.Error("Required flag --input not provided")
+				return
+			}
+
+			db, err := initDB(cmd)
+			if err != nil {
+				slog.Error("Error initializing database: ", slog.Any("error", err))
+				return
+			}
+
+			input, cleanup, err := fetchBackup(input)
+			if err != nil {
+				slog.Error("Error downloading backup", slog.Any("error", err))
+				return
+			}
+			defer cleanup()
+
+			truncate, _ := cmd.Flags().GetBool("truncate")
+			upsert, _ := cmd.Flags().GetBool("upsert")
+			batchSize, _ := cmd.Flags().GetInt("batch-size")
+
+			opts := structers.RestoreOptions{
+				Input:     input,
+				Truncate:  truncate,
+				Upsert:    upsert,
+				BatchSize: batchSize,
+				Confirm: func(truncated []string) bool {
+					return confirmDestructive(cmd, db, "truncate the restored tables and the tables referencing them", truncated)
+				},
+			}
+
+			// only needed for encrypted archives, Restore reports a missing key when it is
+			if c.Env("KEY") != "" {
+				if opts.Key, err = appKey(); err != nil {
+					slog.Error("Error reading encryption key", slog.Any("error", err))
+					return
+				}
+			}
+
+			if err := registry.Database.Restore(db, opts); err != nil {
+				slog.Error("Restore failed", slog.Any("error", err))
+			}
+		},
+	}
+
+	cmd.Flags().StringP("input", "i", "", "Directory of backup files or a backup archive, local or like s3://bucket/prefix/20060102 (required)")
+	cmd.Flags().Bool("truncate", false, "Truncate the tables before restoring")
+	cmd.Flags().Bool("upsert", false, "Update existing rows by primary key instead of failing")
+	cmd.Flags().Int("batch-size", structers.DefaultBackupBatchSize, "Number of rows inserted per query")
+	addDestructiveFlags(cmd)
+
+	return cmd
+}
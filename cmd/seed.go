@@ -1,9 +1,18 @@
 package cmd
 
 import (
+	"fmt"
 	"log/slog"
+	"math/rand/v2"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/spf13/cobra"
+	"webservices/packages/factory"
+	"webservices/packages/faker"
+	"webservices/packages/structers"
 	"webservices/registry"
 )
 
@@ -19,17 +28,143 @@ func NewDBSeedCmd() *cobra.Command {
 				return
 			}
 
-			for _, f := range registry.Database.GetFactories() {
-				if err := f(db); err != nil {
-					slog.Error("Error factory", slog.Any("error", err))
-					continue
+			// fixtures are declared data, they replace the seeders
+			if fixtures, _ := cmd.Flags().GetString("fixtures"); fixtures != "" {
+				results, err := registry.Database.LoadFixtures(db, fixtures)
+				if err != nil {
+					slog.Error("Loading fixtures failed", slog.Any("error", err))
+					return
 				}
+
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "TABLE\tROWS")
+				for _, r := range results {
+					fmt.Fprintf(w, "%s\t%d\n", r.Table, r.Rows)
+				}
+				w.Flush()
+
+				slog.Info("✅ Fixtures loaded successfully", slog.String("path", fixtures))
+				return
+			}
+
+			seed, _ := cmd.Flags().GetUint64("seed")
+			if !cmd.Flags().Changed("seed") {
+				seed = rand.Uint64()
+			}
+			faker.Seed(seed)
+
+			locale, _ := cmd.Flags().GetString("locale")
+			if err := faker.SetLocale(locale); err != nil {
+				slog.Error("Error setting faker locale", slog.Any("error", err))
+				return
+			}
+
+			value, _ := cmd.Flags().GetString("reference")
+			reference, err := seedReference(value, time.Now())
+			if err != nil {
+				slog.Error("Invalid --reference", slog.Any("error", err))
+				return
+			}
+			faker.SetReference(reference)
+
+			// reproduce the same dataset with --seed and --reference
+			slog.Info("Seeding fake data",
+				slog.Uint64("seed", seed),
+				slog.String("reference", reference.Format(time.DateOnly)),
+				slog.String("locale", locale))
+
+			count, _ := cmd.Flags().GetInt("count")
+			only, _ := cmd.Flags().GetStringSlice("only")
+			except, _ := cmd.Flags().GetStringSlice("except")
+
+			batchSize, _ := cmd.Flags().GetInt("batch-size")
+			transaction, _ := cmd.Flags().GetBool("transaction")
+
+			opts := structers.SeedOptions{
+				Only:        only,
+				Except:      except,
+				Count:       count,
+				BatchSize:   batchSize,
+				Transaction: transaction,
+			}
+
+			bar := &progressBar{}
+			opts.Progress = bar.progress(os.Stdout)
+
+			results, err := registry.Database.Seed(db, opts)
+			bar.done()
+
+			if len(results) > 0 {
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "SEEDER\tROWS\tTIME")
+				for _, r := range results {
+					fmt.Fprintf(w, "%s\t%d\t%s\n", r.Seeder, r.Rows, r.Duration.Round(time.Millisecond))
+				}
+				w.Flush()
+			}
+
+			if err != nil {
+				slog.Error("Seeding failed", slog.Any("error", err))
+				return
 			}
 
 			slog.Info("Database seeding completed!")
 		},
 	}
 
-	cmd.Flags().IntP("count", "c", 1, "Number of records to seed")
+	cmd.Flags().IntP("count", "c", 1, "Number of records each seeder creates")
+	cmd.Flags().StringSlice("only", nil, "Only run these seeders, e.g. users,orders")
+	cmd.Flags().StringSlice("except", nil, "Skip these seeders")
+	cmd.Flags().Uint64("seed", 0, "Seed of the fake data generator, the same seed reproduces the same data (random by default)")
+	cmd.Flags().String("locale", "en", "Locale of the fake data: en, id")
+	cmd.Flags().String("reference", "", "Date the fake dates are relative to, e.g. 2025-01-31 (today by default)")
+	cmd.Flags().Int("batch-size", factory.DefaultBatchSize, "Number of records factories save per INSERT")
+	cmd.Flags().Bool("transaction", false, "Run every seeder in one transaction, rolled back when one fails")
+	cmd.Flags().String("fixtures", "", "Load YAML/JSON fixtures from a directory or file instead of running the seeders")
 	return cmd
 }
+
+// helper
+
+// seedReference is the date fake dates are relative to, the --reference value or else the day of now.
+func seedReference(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return now.UTC().Truncate(24 * time.Hour), nil
+	}
+	reference, err := time.Parse(time.DateOnly, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("expected a date like 2025-01-31, got %q", value)
+	}
+	return reference, nil
+}
+
+// progressBar draws the records saved by each seeder on its own line.
+type progressBar struct {
+	seeder string
+}
+
+// progress is the progress callback of the seeders, nil unless out is a terminal:
+// the bar redraws its line, otherwise each seeder is logged.
+func (p *progressBar) progress(out *os.File) func(seeder string, saved, total int) {
+	if !isTerminal(out) {
+		return nil
+	}
+	return p.update
+}
+
+func (p *progressBar) update(seeder string, saved, total int) {
+	if p.seeder != "" && p.seeder != seeder {
+		fmt.Println()
+	}
+	p.seeder = seeder
+
+	const width = 30
+	filled := min(saved*width/max(total, 1), width)
+	fmt.Printf("\r%-16s [%s%s] %d/%d", seeder, strings.Repeat("=", filled), strings.Repeat(" ", width-filled), saved, total)
+}
+
+func (p *progressBar) done() {
+	if p.seeder != "" {
+		fmt.Println()
+	}
+}
@@ -0,0 +1,86 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSeedReference(t *testing.T) {
+	now := time.Date(2026, 3, 14, 15, 9, 26, 0, time.FixedZone("WIB", 7*60*60))
+
+	tests := []struct {
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{value: "", want: time.Date(2026, 3, 14, 0, 0, 0, 0, time.UTC)},
+		{value: "2025-01-31", want: time.Date(2025, 1, 31, 0, 0, 0, 0, time.UTC)},
+		{value: "31/01/2025", wantErr: true},
+		{value: "2025-01-31T10:00:00Z", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := seedReference(tt.value, now)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("reference = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(tt.want) {
+				t.Errorf("reference = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProgressBarOnlyOnATerminal(t *testing.T) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer w.Close()
+
+	file, err := os.Create(filepath.Join(t.TempDir(), "seed.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	for name, out := range map[string]*os.File{"pipe": w, "file": file} {
+		bar := &progressBar{}
+		if bar.progress(out) != nil {
+			t.Errorf("progress bar drawn to a %s", name)
+		}
+	}
+}
+
+func TestProgressBar(t *testing.T) {
+	output := captureStdout(t, func() {
+		bar := &progressBar{}
+		bar.update("users", 0, 10)
+		bar.update("users", 5, 10)
+		bar.update("orders", 20, 20)
+		bar.done()
+	})
+
+	lines := strings.Split(output, "\n")
+	want := []string{
+		"\rusers            [                              ] 0/10" +
+			"\rusers            [===============               ] 5/10",
+		"\rorders           [==============================] 20/20",
+		"",
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("output = %q, want %q", lines, want)
+	}
+}
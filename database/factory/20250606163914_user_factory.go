@@ -1,32 +1,27 @@
 package factory
+
 import (
-	"gorm.io/gorm"
-)
+	"fmt"
 
-type UserFactory struct {
-	db *gorm.DB
-}
+	"webservices/packages/factory"
+	"webservices/packages/faker"
+	"webservices/src/model"
 
-func NewUserFactory(db *gorm.DB) *UserFactory {
-	return &UserFactory{db: db}
-}
-
-func (f *UserFactory) Create() error {
-	// Implement your factory logic here
-	// Example:
-	// data := &models.User{
-	//     Field1: faker.Word(),
-	//     Field2: faker.Email(),
-	// }
-	// return f.db.Create(data).Error
-	return nil
-}
+	"gorm.io/gorm"
+)
 
-func (f *UserFactory) CreateBatch(count int) error {
-	for range count {
-		if err := f.Create(); err != nil {
-			return err
+// NewUserFactory builds User records, use it in a seeder at [registry/database.go]:
+//
+//	factory.NewUserFactory(db).State("admin").CreateBatch(count)
+func NewUserFactory(db *gorm.DB) *factory.Factory[model.User] {
+	return factory.Define(db, func(seq int) model.User {
+		return model.User{
+			// unique even when the faker repeats itself, the name is filled from its tag
+			Email: fmt.Sprintf("%d.%s", seq, faker.Email()),
+			Role:  "member",
 		}
-	}
-	return nil
+	}).
+		DefineState("admin", func(u *model.User) {
+			u.Role = "admin"
+		})
 }
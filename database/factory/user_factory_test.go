@@ -0,0 +1,50 @@
+package factory
+
+import (
+	"strings"
+	"testing"
+
+	"webservices/src/model"
+)
+
+func TestUserFactory(t *testing.T) {
+	tests := []struct {
+		name     string
+		build    func() ([]*model.User, error)
+		wantRole string
+	}{
+		{
+			name:     "default",
+			build:    func() ([]*model.User, error) { return NewUserFactory(nil).MakeMany(3) },
+			wantRole: "member",
+		},
+		{
+			name:     "admin state",
+			build:    func() ([]*model.User, error) { return NewUserFactory(nil).State("admin").MakeMany(3) },
+			wantRole: "admin",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			users, err := tt.build()
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			emails := make(map[string]bool)
+			for i, u := range users {
+				if u.Name == "" {
+					t.Errorf("user %d has no name", i)
+				}
+				if !strings.Contains(u.Email, "@") || emails[u.Email] {
+					t.Errorf("user %d email = %q, want a unique email", i, u.Email)
+				}
+				emails[u.Email] = true
+				if u.Role != tt.wantRole {
+					t.Errorf("user %d role = %q, want %q", i, u.Role, tt.wantRole)
+				}
+			}
+		})
+	}
+}
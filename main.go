@@ -46,9 +46,18 @@ func NewCmd() *cobra.Command {
 
 	root.AddCommand(cmd.NewServeCmd())
 	root.AddCommand(cmd.NewMigrateCmd())
+	root.AddCommand(cmd.NewMigrateStatusCmd())
+	root.AddCommand(cmd.NewMigrateRollbackCmd())
+	root.AddCommand(cmd.NewMigrateResetCmd())
+	root.AddCommand(cmd.NewDBEnumsCmd())
+	root.AddCommand(cmd.NewDBDiffCmd())
 	root.AddCommand(cmd.NewDBBackupCmd())
+	root.AddCommand(cmd.NewDBBackupVerifyCmd())
+	root.AddCommand(cmd.NewDBBackupPruneCmd())
+	root.AddCommand(cmd.NewDBRestoreCmd())
 	root.AddCommand(cmd.NewDBSeedCmd())
 	root.AddCommand(cmd.NewMDBFactoryCmd())
+	root.AddCommand(cmd.NewMakeMigrationCmd())
 	root.AddCommand(cmd.NewMakeModel())
 	root.AddCommand(cmd.NewMakeRepo())
 	root.AddCommand(cmd.NewMakeServices())
@@ -0,0 +1,233 @@
+package cipher
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hkdf"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// streams are split in chunks sealed with AES-GCM, the nonce is a random prefix followed by
+// the chunk counter and the last chunk is flagged so a truncated stream is detected.
+// Streams are sealed with a key derived for them.
+const (
+	streamMagic     = "WSENC2"
+	streamChunkSize = 64 * 1024
+	noncePrefixSize = 8
+)
+
+// key purposes, each use of the KEY gets its own subkey
+const (
+	PurposeStream = "webservices stream encryption"
+	PurposeMask   = "webservices backup masking"
+)
+
+var ErrInvalidStream = errors.New("invalid or corrupted encrypted stream")
+
+// DecodeKey decodes the application KEY generated by `generate:key`.
+func DecodeKey(key string) ([]byte, error) {
+	key = strings.Trim(strings.TrimSpace(key), `"`)
+	if key == "" {
+		return nil, errors.New("KEY is empty, run `generate:key` first")
+	}
+
+	decoded, err := base64.URLEncoding.DecodeString(key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid KEY: %v", err)
+	}
+	return decoded, nil
+}
+
+// DeriveKey derives a 32 bytes subkey of key for one purpose with HKDF-SHA256,
+// so the same KEY never serves two purposes.
+func DeriveKey(key []byte, purpose string) ([]byte, error) {
+	if len(key) == 0 {
+		return nil, errors.New("KEY is empty, run `generate:key` first")
+	}
+	return hkdf.Key(sha256.New, key, nil, purpose, 32)
+}
+
+type encryptWriter struct {
+	w       io.Writer
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	buf     []byte
+	closed  bool
+}
+
+// NewEncryptWriter returns a writer encrypting everything written to it into w,
+// Close must be called to write the final chunk.
+func NewEncryptWriter(key []byte, w io.Writer) (io.WriteCloser, error) {
+	subkey, err := DeriveKey(key, PurposeStream)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(subkey)
+	if err != nil {
+		return nil, err
+	}
+
+	prefix := make([]byte, noncePrefixSize)
+	if _, err := io.ReadFull(rand.Reader, prefix); err != nil {
+		return nil, err
+	}
+
+	if _, err := w.Write(append([]byte(streamMagic), prefix...)); err != nil {
+		return nil, err
+	}
+
+	return &encryptWriter{w: w, aead: aead, prefix: prefix, buf: make([]byte, 0, streamChunkSize)}, nil
+}
+
+func (e *encryptWriter) Write(p []byte) (int, error) {
+	if e.closed {
+		return 0, errors.New("write to closed encrypt writer")
+	}
+
+	written := 0
+	for len(p) > 0 {
+		n := copy(e.buf[len(e.buf):cap(e.buf)], p)
+		e.buf = e.buf[:len(e.buf)+n]
+		p = p[n:]
+		written += n
+
+		// keep a full chunk buffered so Close always has data or an empty final chunk to seal
+		if len(e.buf) == cap(e.buf) && len(p) > 0 {
+			if err := e.seal(false); err != nil {
+				return written, err
+			}
+		}
+	}
+	return written, nil
+}
+
+func (e *encryptWriter) Close() error {
+	if e.closed {
+		return nil
+	}
+	e.closed = true
+	return e.seal(true)
+}
+
+func (e *encryptWriter) seal(final bool) error {
+	sealed := e.aead.Seal(nil, nonce(e.prefix, e.counter), e.buf, chunkAAD(final))
+	e.counter++
+	e.buf = e.buf[:0]
+
+	header := make([]byte, 4)
+	binary.BigEndian.PutUint32(header, uint32(len(sealed)))
+	if _, err := e.w.Write(header); err != nil {
+		return err
+	}
+	_, err := e.w.Write(sealed)
+	return err
+}
+
+type decryptReader struct {
+	r       io.Reader
+	aead    cipher.AEAD
+	prefix  []byte
+	counter uint32
+	buf     []byte
+	done    bool
+}
+
+// NewDecryptReader returns a reader decrypting a stream written by NewEncryptWriter.
+func NewDecryptReader(key []byte, r io.Reader) (io.Reader, error) {
+	header := make([]byte, len(streamMagic)+noncePrefixSize)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, ErrInvalidStream
+	}
+
+	if string(header[:len(streamMagic)]) != streamMagic {
+		return nil, ErrInvalidStream
+	}
+
+	subkey, err := DeriveKey(key, PurposeStream)
+	if err != nil {
+		return nil, err
+	}
+
+	aead, err := newAEAD(subkey)
+	if err != nil {
+		return nil, err
+	}
+
+	return &decryptReader{r: r, aead: aead, prefix: header[len(streamMagic):]}, nil
+}
+
+func (d *decryptReader) Read(p []byte) (int, error) {
+	for len(d.buf) == 0 {
+		if d.done {
+			return 0, io.EOF
+		}
+		if err := d.open(); err != nil {
+			return 0, err
+		}
+	}
+
+	n := copy(p, d.buf)
+	d.buf = d.buf[n:]
+	return n, nil
+}
+
+func (d *decryptReader) open() error {
+	header := make([]byte, 4)
+	if _, err := io.ReadFull(d.r, header); err != nil {
+		return ErrInvalidStream // the final chunk is missing
+	}
+
+	size := binary.BigEndian.Uint32(header)
+	if size > streamChunkSize+uint32(d.aead.Overhead()) {
+		return ErrInvalidStream
+	}
+
+	sealed := make([]byte, size)
+	if _, err := io.ReadFull(d.r, sealed); err != nil {
+		return ErrInvalidStream
+	}
+
+	n := nonce(d.prefix, d.counter)
+	plain, err := d.aead.Open(nil, n, sealed, chunkAAD(false))
+	if err != nil {
+		if plain, err = d.aead.Open(nil, n, sealed, chunkAAD(true)); err != nil {
+			return ErrInvalidStream
+		}
+		d.done = true
+	}
+
+	d.counter++
+	d.buf = plain
+	return nil
+}
+
+func newAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func nonce(prefix []byte, counter uint32) []byte {
+	n := make([]byte, noncePrefixSize+4)
+	copy(n, prefix)
+	binary.BigEndian.PutUint32(n[noncePrefixSize:], counter)
+	return n
+}
+
+func chunkAAD(final bool) []byte {
+	if final {
+		return []byte{1}
+	}
+	return []byte{0}
+}
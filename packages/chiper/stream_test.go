@@ -0,0 +1,132 @@
+package cipher
+
+import (
+	"bytes"
+	"crypto/rand"
+	"errors"
+	"io"
+	"testing"
+)
+
+func TestStreamRoundTrip(t *testing.T) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		size int
+	}{
+		{name: "empty", size: 0},
+		{name: "small", size: 10},
+		{name: "exactly one chunk", size: streamChunkSize},
+		{name: "one chunk and a byte", size: streamChunkSize + 1},
+		{name: "several chunks", size: 3*streamChunkSize + 123},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			plain := make([]byte, tt.size)
+			if _, err := rand.Read(plain); err != nil {
+				t.Fatal(err)
+			}
+
+			var sealed bytes.Buffer
+			w, err := NewEncryptWriter(key, &sealed)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if _, err := w.Write(plain); err != nil {
+				t.Fatal(err)
+			}
+			if err := w.Close(); err != nil {
+				t.Fatal(err)
+			}
+
+			r, err := NewDecryptReader(key, bytes.NewReader(sealed.Bytes()))
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := io.ReadAll(r)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !bytes.Equal(got, plain) {
+				t.Errorf("decrypted %d bytes, want the %d written", len(got), len(plain))
+			}
+		})
+	}
+}
+
+func TestStreamRejectsTampering(t *testing.T) {
+	key := make([]byte, 32)
+	plain := bytes.Repeat([]byte("x"), 2*streamChunkSize+10)
+
+	var sealed bytes.Buffer
+	w, err := NewEncryptWriter(key, &sealed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Write(plain)
+	w.Close()
+
+	// the first chunk header sits right after the magic and the nonce prefix
+	firstChunk := len(streamMagic) + noncePrefixSize + 4 + streamChunkSize + 16
+
+	tests := []struct {
+		name   string
+		stream []byte
+		key    []byte
+	}{
+		{name: "truncated before the final chunk", stream: sealed.Bytes()[:firstChunk], key: key},
+		{name: "flipped byte", stream: flipByte(sealed.Bytes(), firstChunk-1), key: key},
+		{name: "wrong key", stream: sealed.Bytes(), key: bytes.Repeat([]byte{1}, 32)},
+		{name: "unknown magic", stream: append([]byte("WSENC1"), sealed.Bytes()[len(streamMagic):]...), key: key},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := NewDecryptReader(tt.key, bytes.NewReader(tt.stream))
+			if err == nil {
+				_, err = io.ReadAll(r)
+			}
+			if !errors.Is(err, ErrInvalidStream) {
+				t.Errorf("error = %v, want %v", err, ErrInvalidStream)
+			}
+		})
+	}
+}
+
+func flipByte(b []byte, i int) []byte {
+	b = bytes.Clone(b)
+	b[i] ^= 0xff
+	return b
+}
+
+func TestDeriveKey(t *testing.T) {
+	key := bytes.Repeat([]byte{7}, 32)
+
+	stream, err := DeriveKey(key, PurposeStream)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mask, err := DeriveKey(key, PurposeMask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := DeriveKey(key, PurposeStream)
+
+	if len(stream) != 32 || len(mask) != 32 {
+		t.Errorf("subkeys of %d and %d bytes, want 32", len(stream), len(mask))
+	}
+	if bytes.Equal(stream, mask) || bytes.Equal(stream, key) || bytes.Equal(mask, key) {
+		t.Error("subkeys of distinct purposes must differ from each other and from the KEY")
+	}
+	if !bytes.Equal(stream, again) {
+		t.Error("subkeys must be deterministic")
+	}
+	if _, err := DeriveKey(nil, PurposeMask); err == nil {
+		t.Error("empty KEY must be rejected")
+	}
+}
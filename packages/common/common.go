@@ -157,3 +157,46 @@ func ToCamelCase(s string) string {
 
 	return result.String()
 }
+
+// ToSnakeCase converts "UserProfile", "user-profile" or "user profile" to "user_profile".
+func ToSnakeCase(s string) string {
+	return strings.Join(splitWords(s), "_")
+}
+
+// ToKebabCase converts "UserProfile", "user_profile" or "user profile" to "user-profile".
+func ToKebabCase(s string) string {
+	return strings.Join(splitWords(s), "-")
+}
+
+// splitWords splits s into lower case words on separators and case changes, "HTTPServer" is "http", "server".
+func splitWords(s string) []string {
+	var words []string
+	var word []rune
+
+	runes := []rune(strings.TrimSpace(s))
+	flush := func() {
+		if len(word) > 0 {
+			words = append(words, strings.ToLower(string(word)))
+			word = word[:0]
+		}
+	}
+
+	for i, r := range runes {
+		if unicode.IsSpace(r) || r == '_' || r == '-' {
+			flush()
+			continue
+		}
+
+		if unicode.IsUpper(r) && i > 0 {
+			prev := runes[i-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				flush()
+			}
+		}
+		word = append(word, r)
+	}
+	flush()
+
+	return words
+}
@@ -0,0 +1,39 @@
+package common
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplitWords(t *testing.T) {
+	tests := []struct {
+		in    string
+		words []string
+		snake string
+		kebab string
+	}{
+		{in: "UserProfile", words: []string{"user", "profile"}, snake: "user_profile", kebab: "user-profile"},
+		{in: "userProfile", words: []string{"user", "profile"}, snake: "user_profile", kebab: "user-profile"},
+		{in: "user_profile", words: []string{"user", "profile"}, snake: "user_profile", kebab: "user-profile"},
+		{in: "user-profile", words: []string{"user", "profile"}, snake: "user_profile", kebab: "user-profile"},
+		{in: " user  profile ", words: []string{"user", "profile"}, snake: "user_profile", kebab: "user-profile"},
+		{in: "HTTPServer", words: []string{"http", "server"}, snake: "http_server", kebab: "http-server"},
+		{in: "UserID", words: []string{"user", "id"}, snake: "user_id", kebab: "user-id"},
+		{in: "user", words: []string{"user"}, snake: "user", kebab: "user"},
+		{in: "", words: nil, snake: "", kebab: ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			if got := splitWords(tt.in); !reflect.DeepEqual(got, tt.words) {
+				t.Errorf("splitWords(%q) = %q, want %q", tt.in, got, tt.words)
+			}
+			if got := ToSnakeCase(tt.in); got != tt.snake {
+				t.Errorf("ToSnakeCase(%q) = %q, want %q", tt.in, got, tt.snake)
+			}
+			if got := ToKebabCase(tt.in); got != tt.kebab {
+				t.Errorf("ToKebabCase(%q) = %q, want %q", tt.in, got, tt.kebab)
+			}
+		})
+	}
+}
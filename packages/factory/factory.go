@@ -0,0 +1,398 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"sync"
+	"sync/atomic"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+	"webservices/packages/faker"
+)
+
+// Factory builds records of model T from default attributes, named states and per-call overrides,
+// in memory with Make or persisted with Create. Methods configuring it return a copy,
+// so a factory can be shared and refined without affecting the others.
+type Factory[T any] struct {
+	db         *gorm.DB
+	definition func(seq int) T
+	states     map[string]func(*T)
+	sequence   *atomic.Int64
+	modifiers  []func(*T) // applied states and overrides, in order
+	parents    []Builder
+	children   []children
+	batchSize  int
+	err        error
+}
+
+// DefaultBatchSize is the number of records saved per INSERT when neither the factory
+// nor the database session, see gorm.Session.CreateBatchSize, set one.
+const DefaultBatchSize = 500
+
+// Builder is a factory of any model, so factories of different models can be related with For and Has.
+type Builder interface {
+	modelSchema() (*schema.Schema, error)
+	produce(count int, persist bool, link func(i int, record reflect.Value) error) ([]reflect.Value, error)
+}
+
+type children struct {
+	builder Builder
+	count   int
+}
+
+// Define returns the factory of T, definition returns the default attributes of a record,
+// seq counts the records built by the factory from 1, handy for unique values.
+func Define[T any](db *gorm.DB, definition func(seq int) T) *Factory[T] {
+	return &Factory[T]{
+		db:         db,
+		definition: definition,
+		states:     make(map[string]func(*T)),
+		sequence:   new(atomic.Int64),
+	}
+}
+
+// DefineState registers a named state applied with State, e.g. "admin" or "suspended".
+func (f *Factory[T]) DefineState(name string, state func(*T)) *Factory[T] {
+	c := f.clone()
+	c.states[name] = state
+	return c
+}
+
+// State applies the named states on top of the default attributes.
+func (f *Factory[T]) State(names ...string) *Factory[T] {
+	c := f.clone()
+	for _, name := range names {
+		state, ok := f.states[name]
+		if !ok {
+			c.err = fmt.Errorf("unknown state %q of %s factory", name, modelName[T]())
+			return c
+		}
+		c.modifiers = append(c.modifiers, state)
+	}
+	return c
+}
+
+// With overrides attributes of every record built by the returned factory.
+func (f *Factory[T]) With(override func(*T)) *Factory[T] {
+	c := f.clone()
+	c.modifiers = append(c.modifiers, override)
+	return c
+}
+
+// For builds the records with a parent from the given factory, shared by the records of one call,
+// and sets their foreign key from the belongs-to or has-one/has-many relationship between both models.
+func (f *Factory[T]) For(parent Builder) *Factory[T] {
+	c := f.clone()
+	c.parents = append(c.parents, parent)
+	return c
+}
+
+// Has builds count children with the given factory for every record, linked by their foreign key.
+func (f *Factory[T]) Has(child Builder, count int) *Factory[T] {
+	c := f.clone()
+	c.children = append(c.children, children{builder: child, count: count})
+	return c
+}
+
+// BatchSize sets the number of records saved per INSERT.
+func (f *Factory[T]) BatchSize(size int) *Factory[T] {
+	c := f.clone()
+	c.batchSize = size
+	return c
+}
+
+// Make builds a record in memory, parents and children included, without saving anything.
+func (f *Factory[T]) Make(overrides ...func(*T)) (*T, error) {
+	records, err := f.build(1, false, overrides, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	return records[0], nil
+}
+
+// MakeMany builds count records in memory.
+func (f *Factory[T]) MakeMany(count int, overrides ...func(*T)) ([]*T, error) {
+	return f.build(count, false, overrides, nil, true)
+}
+
+// Create builds and saves a record, its parents first and its children after it.
+func (f *Factory[T]) Create(overrides ...func(*T)) (*T, error) {
+	records, err := f.build(1, true, overrides, nil, true)
+	if err != nil {
+		return nil, err
+	}
+	return records[0], nil
+}
+
+// CreateMany builds count records in memory and saves them in batches.
+func (f *Factory[T]) CreateMany(count int, overrides ...func(*T)) ([]*T, error) {
+	return f.build(count, true, overrides, nil, true)
+}
+
+// CreateBatch saves count records, for seeders which don't need them back.
+func (f *Factory[T]) CreateBatch(count int) error {
+	_, err := f.build(count, true, nil, nil, true)
+	return err
+}
+
+// helper
+
+func (f *Factory[T]) clone() *Factory[T] {
+	c := *f
+	c.states = maps.Clone(f.states)
+	c.modifiers = append([]func(*T){}, f.modifiers...)
+	c.parents = append([]Builder{}, f.parents...)
+	c.children = append([]children{}, f.children...)
+	return &c
+}
+
+func (f *Factory[T]) produce(count int, persist bool, link func(i int, record reflect.Value) error) ([]reflect.Value, error) {
+	records, err := f.build(count, persist, nil, link, false)
+	if err != nil {
+		return nil, err
+	}
+
+	values := make([]reflect.Value, len(records))
+	for i, record := range records {
+		values[i] = reflect.ValueOf(record)
+	}
+	return values, nil
+}
+
+// build makes count records in memory and saves them in batches, link sets the foreign keys of children
+// before they are saved. Only top level calls report their progress, not the parents and children they build.
+func (f *Factory[T]) build(count int, persist bool, overrides []func(*T), link func(int, reflect.Value) error, top bool) ([]*T, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	if persist && f.db == nil {
+		return nil, fmt.Errorf("%s factory has no database to create records in", modelName[T]())
+	}
+
+	own, err := f.modelSchema()
+	if err != nil {
+		return nil, err
+	}
+
+	// parents are built once per call and shared by its records
+	parents := make([]reflect.Value, len(f.parents))
+	parentRelations := make([]*schema.Relationship, len(f.parents))
+	for i, p := range f.parents {
+		ps, err := p.modelSchema()
+		if err != nil {
+			return nil, err
+		}
+		if parentRelations[i], err = relationship(ps, own, false); err != nil {
+			return nil, err
+		}
+
+		parent, err := p.produce(1, persist, nil)
+		if err != nil {
+			return nil, err
+		}
+		parents[i] = parent[0]
+	}
+
+	childRelations := make([]*schema.Relationship, len(f.children))
+	for i, c := range f.children {
+		cs, err := c.builder.modelSchema()
+		if err != nil {
+			return nil, err
+		}
+		if childRelations[i], err = relationship(own, cs, true); err != nil {
+			return nil, err
+		}
+	}
+
+	records := make([]*T, count)
+	for i := range records {
+		record := new(T)
+		*record = f.definition(int(f.sequence.Add(1)))
+
+		// tagged fields the definition left empty are faked, e.g. `faker:"email"`
+		if err := faker.Fill(record); err != nil {
+			return nil, err
+		}
+		for _, m := range f.modifiers {
+			m(record)
+		}
+
+		value := reflect.ValueOf(record)
+		for j, parent := range parents {
+			if err := linkForeignKey(f.db, parentRelations[j], parent, value); err != nil {
+				return nil, err
+			}
+		}
+		if link != nil {
+			if err := link(i, value); err != nil {
+				return nil, err
+			}
+		}
+
+		// overrides win over everything else, foreign keys included
+		for _, o := range overrides {
+			o(record)
+		}
+
+		records[i] = record
+	}
+
+	if persist {
+		if err := f.insert(records, top); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, record := range records {
+		for j, parent := range parents {
+			if err := associate(f.db, parentRelations[j], parent, reflect.ValueOf(record)); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	// the children of every record are built and saved together, in batches as well
+	for j, c := range f.children {
+		rel := childRelations[j]
+		children, err := c.builder.produce(len(records)*c.count, persist, func(i int, child reflect.Value) error {
+			return linkForeignKey(f.db, rel, reflect.ValueOf(records[i/c.count]), child)
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for i, child := range children {
+			if err := associate(f.db, rel, reflect.ValueOf(records[i/c.count]), child); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return records, nil
+}
+
+// insert saves the records with one INSERT per batch, the batch size of the factory,
+// or else the CreateBatchSize of the database session, or DefaultBatchSize.
+func (f *Factory[T]) insert(records []*T, report bool) error {
+	size := f.batchSize
+	if size <= 0 {
+		size = f.db.CreateBatchSize
+	}
+	if size <= 0 {
+		size = DefaultBatchSize
+	}
+
+	progress := progressOf(f.db)
+	if !report || progress == nil {
+		progress = func(int, int) {}
+	}
+
+	// the session would split a batch larger than its own batch size again
+	db := f.db.Session(&gorm.Session{CreateBatchSize: size})
+
+	progress(0, len(records))
+	for batch := range slices.Chunk(records, size) {
+		if err := db.Create(&batch).Error; err != nil {
+			return fmt.Errorf("failed to create %s: %v", modelName[T](), err)
+		}
+		progress(len(batch), 0)
+	}
+	return nil
+}
+
+var schemaCache sync.Map
+
+// modelSchema parses T with the naming strategy of the database, or the default one without database.
+func (f *Factory[T]) modelSchema() (*schema.Schema, error) {
+	if f.db != nil {
+		stmt := &gorm.Statement{DB: f.db}
+		if err := stmt.Parse(new(T)); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", modelName[T](), err)
+		}
+		return stmt.Schema, nil
+	}
+
+	s, err := schema.Parse(new(T), &schemaCache, schema.NamingStrategy{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %v", modelName[T](), err)
+	}
+	return s, nil
+}
+
+// relationship finds how child references parent, a belongs-to of the child or a has-one/has-many of the parent.
+// fromParent prefers the relationship declared by the parent, whose association field is then filled.
+func relationship(parent, child *schema.Schema, fromParent bool) (*schema.Relationship, error) {
+	var belongsTo, has *schema.Relationship
+
+	for _, rel := range child.Relationships.BelongsTo {
+		if belongsTo == nil && rel.FieldSchema.ModelType == parent.ModelType {
+			belongsTo = rel
+		}
+	}
+	for _, rel := range append(parent.Relationships.HasOne, parent.Relationships.HasMany...) {
+		if has == nil && rel.FieldSchema.ModelType == child.ModelType {
+			has = rel
+		}
+	}
+
+	switch {
+	case fromParent && has != nil:
+		return has, nil
+	case belongsTo != nil:
+		return belongsTo, nil
+	case has != nil:
+		return has, nil
+	}
+	return nil, fmt.Errorf("%s has no belongs-to, has-one or has-many relationship with %s", child.Name, parent.Name)
+}
+
+// linkForeignKey copies the primary key of parent into the foreign key of child, either side declares the relationship.
+func linkForeignKey(db *gorm.DB, rel *schema.Relationship, parent, child reflect.Value) error {
+	ctx := statementContext(db)
+
+	for _, ref := range rel.References {
+		var value any = ref.PrimaryValue // polymorphic type column
+		if ref.PrimaryKey != nil {
+			value, _ = ref.PrimaryKey.ValueOf(ctx, parent.Elem())
+		}
+		if err := ref.ForeignKey.Set(ctx, child.Elem(), value); err != nil {
+			return fmt.Errorf("failed to set %s.%s: %v", rel.FieldSchema.Name, ref.ForeignKey.Name, err)
+		}
+	}
+	return nil
+}
+
+// associate sets the association field of the side declaring the relationship, so the built records are connected.
+func associate(db *gorm.DB, rel *schema.Relationship, parent, child reflect.Value) error {
+	ctx := statementContext(db)
+
+	switch rel.Type {
+	case schema.BelongsTo:
+		return rel.Field.Set(ctx, child.Elem(), parent.Interface())
+	case schema.HasOne:
+		return rel.Field.Set(ctx, parent.Elem(), child.Interface())
+	case schema.HasMany:
+		field := rel.Field.ReflectValueOf(ctx, parent.Elem())
+		if field.Type().Elem().Kind() == reflect.Ptr {
+			field.Set(reflect.Append(field, child))
+		} else {
+			field.Set(reflect.Append(field, child.Elem()))
+		}
+	}
+	return nil
+}
+
+func statementContext(db *gorm.DB) context.Context {
+	if db != nil && db.Statement != nil && db.Statement.Context != nil {
+		return db.Statement.Context
+	}
+	return context.Background()
+}
+
+func modelName[T any]() string {
+	return reflect.TypeFor[T]().Name()
+}
@@ -0,0 +1,244 @@
+package factory
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils/tests"
+)
+
+type account struct {
+	ID   uint
+	Name string
+	Role string
+}
+
+func TestDefineStateReturnsACopy(t *testing.T) {
+	base := Define[account](nil, func(seq int) account {
+		return account{Name: "account", Role: "member"}
+	})
+	admin := base.DefineState("admin", func(a *account) { a.Role = "admin" })
+	owner := admin.DefineState("admin", func(a *account) { a.Role = "owner" })
+
+	tests := []struct {
+		name    string
+		factory *Factory[account]
+		want    string
+		wantErr bool
+	}{
+		{name: "base has no state", factory: base, wantErr: true},
+		{name: "state of the copy", factory: admin, want: "admin"},
+		{name: "redefined in a copy of the copy", factory: owner, want: "owner"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record, err := tt.factory.State("admin").Make()
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("role = %s, want an unknown state error", record.Role)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if record.Role != tt.want {
+				t.Errorf("role = %s, want %s", record.Role, tt.want)
+			}
+		})
+	}
+}
+
+type team struct {
+	ID      uint
+	Name    string
+	Members []member
+}
+
+type member struct {
+	ID     uint
+	TeamID uint
+	Team   *team
+	Name   string
+	Role   string
+}
+
+// teams and members number their ids from 1 themselves, nothing is saved by Make
+func teamFactory(db *gorm.DB) *Factory[team] {
+	return Define(db, func(seq int) team { return team{ID: uint(seq), Name: fmt.Sprintf("team %d", seq)} })
+}
+
+func memberFactory(db *gorm.DB) *Factory[member] {
+	return Define(db, func(seq int) member {
+		return member{ID: uint(seq), Name: fmt.Sprintf("member %d", seq), Role: "member"}
+	})
+}
+
+func TestForLinksTheParent(t *testing.T) {
+	teams := teamFactory(nil)
+	teams.MakeMany(4) // the next team is 5
+
+	members, err := memberFactory(nil).For(teams).MakeMany(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, m := range members {
+		if m.TeamID != 5 || m.Team == nil || m.Team.ID != 5 {
+			t.Errorf("member %d belongs to team %d (%+v), want the team 5 shared by the call", m.ID, m.TeamID, m.Team)
+		}
+	}
+	if members[0].Team != members[2].Team {
+		t.Error("members of one call got different teams")
+	}
+
+	// the next call builds another parent
+	other, err := memberFactory(nil).For(teams).Make()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if other.TeamID != 6 {
+		t.Errorf("member of the next call belongs to team %d, want 6", other.TeamID)
+	}
+}
+
+func TestHasLinksTheChildren(t *testing.T) {
+	teams, err := teamFactory(nil).Has(memberFactory(nil), 2).MakeMany(2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, tm := range teams {
+		if len(tm.Members) != 2 {
+			t.Fatalf("team %d has %d members, want 2", tm.ID, len(tm.Members))
+		}
+		for _, m := range tm.Members {
+			if m.TeamID != tm.ID {
+				t.Errorf("member %d of team %d has team id %d", m.ID, tm.ID, m.TeamID)
+			}
+		}
+	}
+	if teams[0].Members[0].ID == teams[1].Members[0].ID {
+		t.Error("teams share their members")
+	}
+}
+
+func TestWithOverrides(t *testing.T) {
+	leads := memberFactory(nil).With(func(m *member) { m.Role = "lead" })
+
+	tests := []struct {
+		name      string
+		overrides []func(*member)
+		wantRole  string
+		wantTeam  uint
+	}{
+		{name: "factory override", wantRole: "lead", wantTeam: 1},
+		{name: "call override wins", overrides: []func(*member){func(m *member) { m.Role = "owner" }}, wantRole: "owner", wantTeam: 1},
+		{name: "call override wins over the foreign key", overrides: []func(*member){func(m *member) { m.TeamID = 42 }}, wantRole: "lead", wantTeam: 42},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := leads.For(teamFactory(nil)).Make(tt.overrides...)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if m.Role != tt.wantRole || m.TeamID != tt.wantTeam {
+				t.Errorf("member = %s of team %d, want %s of team %d", m.Role, m.TeamID, tt.wantRole, tt.wantTeam)
+			}
+		})
+	}
+
+	if m, _ := memberFactory(nil).Make(); m.Role != "member" {
+		t.Errorf("override leaked into the base factory, role = %s", m.Role)
+	}
+}
+
+func TestMakeAndCreate(t *testing.T) {
+	db, inserts := dryRunDB(t)
+
+	if _, err := memberFactory(nil).Create(); err == nil || !strings.Contains(err.Error(), "no database") {
+		t.Errorf("Create without database error = %v, want no database", err)
+	}
+
+	if _, err := teamFactory(db).Has(memberFactory(db), 2).Make(); err != nil {
+		t.Fatal(err)
+	}
+	if len(*inserts) != 0 {
+		t.Fatalf("Make saved %v", *inserts)
+	}
+
+	if _, err := memberFactory(db).For(teamFactory(db)).Create(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := teamFactory(db).Has(memberFactory(db), 3).CreateMany(2); err != nil {
+		t.Fatal(err)
+	}
+
+	// parents are saved first, children after their parents and together in one batch
+	want := []string{"teams 1", "members 1", "teams 2", "members 6"}
+	if !reflect.DeepEqual(*inserts, want) {
+		t.Errorf("inserts = %v, want %v", *inserts, want)
+	}
+}
+
+func TestCreateBatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		count     int
+		batchSize int
+		session   int
+		want      []string
+	}{
+		{name: "one batch", count: 3, want: []string{"members 3"}},
+		{name: "factory batch size", count: 5, batchSize: 2, want: []string{"members 2", "members 2", "members 1"}},
+		{name: "session batch size", count: 4, session: 3, want: []string{"members 3", "members 1"}},
+		{name: "factory wins over the session", count: 4, batchSize: 4, session: 3, want: []string{"members 4"}},
+		{name: "nothing", count: 0, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, inserts := dryRunDB(t)
+			if tt.session > 0 {
+				db = db.Session(&gorm.Session{CreateBatchSize: tt.session})
+			}
+
+			if err := memberFactory(db).BatchSize(tt.batchSize).CreateBatch(tt.count); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(*inserts, tt.want) {
+				t.Errorf("inserts = %v, want %v", *inserts, tt.want)
+			}
+		})
+	}
+}
+
+// helper
+
+// dryRunDB returns a database which only builds statements, inserts records the table and the rows of every INSERT.
+func dryRunDB(t *testing.T) (*gorm.DB, *[]string) {
+	t.Helper()
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	inserts := new([]string)
+	err = db.Callback().Create().After("gorm:create").Register("test:inserts", func(tx *gorm.DB) {
+		rows := 1
+		if tx.Statement.ReflectValue.Kind() == reflect.Slice {
+			rows = tx.Statement.ReflectValue.Len()
+		}
+		*inserts = append(*inserts, fmt.Sprintf("%s %d", tx.Statement.Table, rows))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db, inserts
+}
@@ -0,0 +1,23 @@
+package factory
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type progressKey struct{}
+
+// WithProgress returns a context whose factories report their top level Create calls,
+// the parents and children they build are not counted. A call first reports the records
+// it is about to save as added, then the records saved by each batch.
+func WithProgress(ctx context.Context, progress func(saved, added int)) context.Context {
+	return context.WithValue(ctx, progressKey{}, progress)
+}
+
+// helper
+
+func progressOf(db *gorm.DB) func(saved, added int) {
+	progress, _ := statementContext(db).Value(progressKey{}).(func(saved, added int))
+	return progress
+}
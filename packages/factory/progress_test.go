@@ -0,0 +1,34 @@
+package factory
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestWithProgress(t *testing.T) {
+	db, _ := dryRunDB(t)
+
+	var calls []string
+	db = db.WithContext(WithProgress(context.Background(), func(saved, added int) {
+		calls = append(calls, fmt.Sprintf("+%d saved %d", added, saved))
+	}))
+
+	// the parents and children built along are not reported
+	members := memberFactory(db).For(teamFactory(db)).BatchSize(2)
+	if _, err := members.CreateMany(5); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := teamFactory(db).Has(memberFactory(db), 3).Create(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"+5 saved 0", "+0 saved 2", "+0 saved 2", "+0 saved 1",
+		"+1 saved 0", "+0 saved 1",
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("progress = %v, want %v", calls, want)
+	}
+}
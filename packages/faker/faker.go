@@ -0,0 +1,257 @@
+package faker
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Faker generates fake data of a locale, the same seed always generates the same values in the same order.
+// It is safe for concurrent use, values are only reproducible when generated in a fixed order.
+type Faker struct {
+	mu        sync.Mutex
+	rand      *rand.Rand
+	locale    *Locale
+	reference time.Time
+}
+
+// Epoch is the time dates are generated relative to until SetReference moves it,
+// a fixed time so a seed generates the same dates on any day.
+var Epoch = time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+
+var words = []string{
+	"lorem", "ipsum", "dolor", "sit", "amet", "consectetur", "adipiscing", "elit", "sed", "do",
+	"eiusmod", "tempor", "incididunt", "ut", "labore", "et", "dolore", "magna", "aliqua", "enim",
+	"ad", "minim", "veniam", "quis", "nostrud", "exercitation", "ullamco", "laboris", "nisi", "aliquip",
+	"ex", "ea", "commodo", "consequat", "duis", "aute", "irure", "in", "reprehenderit", "voluptate",
+	"velit", "esse", "cillum", "fugiat", "nulla", "pariatur", "excepteur", "sint", "occaecat", "cupidatat",
+}
+
+// New returns a faker of the en locale seeded with seed.
+func New(seed uint64) *Faker {
+	return &Faker{rand: newRand(seed), locale: locales["en"], reference: Epoch}
+}
+
+// Seed restarts the generator, with the same seed the same values are generated again.
+func (f *Faker) Seed(seed uint64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.rand = newRand(seed)
+}
+
+// SetReference moves the time Past, Future and the date generator are relative to, e.g. time.Now(),
+// dates are then only reproducible with the same reference.
+func (f *Faker) SetReference(t time.Time) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.reference = t
+}
+
+// SetLocale switches the data names, addresses and phone numbers are generated from.
+func (f *Faker) SetLocale(name string) error {
+	l, err := locale(name)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.locale = l
+	return nil
+}
+
+func (f *Faker) FirstName() string {
+	return with(f, func(r *rand.Rand, l *Locale) string { return pick(r, l.FirstNames) })
+}
+
+func (f *Faker) LastName() string {
+	return with(f, func(r *rand.Rand, l *Locale) string { return pick(r, l.LastNames) })
+}
+
+func (f *Faker) Name() string {
+	return with(f, func(r *rand.Rand, l *Locale) string {
+		return pick(r, l.FirstNames) + " " + pick(r, l.LastNames)
+	})
+}
+
+func (f *Faker) Username() string {
+	return with(f, func(r *rand.Rand, l *Locale) string {
+		return strings.ToLower(pick(r, l.FirstNames)) + fmt.Sprint(r.IntN(10000))
+	})
+}
+
+// Email is an address of a reserved example domain, it never reaches a real mailbox.
+func (f *Faker) Email() string {
+	return with(f, func(r *rand.Rand, l *Locale) string {
+		return fmt.Sprintf("%s.%s%d@%s", slug(pick(r, l.FirstNames)), slug(pick(r, l.LastNames)), r.IntN(1000), pick(r, l.Domains))
+	})
+}
+
+func (f *Faker) Phone() string {
+	return with(f, func(r *rand.Rand, l *Locale) string { return digits(r, l.Phone) })
+}
+
+func (f *Faker) Street() string {
+	return with(f, func(r *rand.Rand, l *Locale) string { return pick(r, l.Streets) })
+}
+
+func (f *Faker) City() string {
+	return with(f, func(r *rand.Rand, l *Locale) string { return pick(r, l.Cities) })
+}
+
+func (f *Faker) PostCode() string {
+	return with(f, func(r *rand.Rand, l *Locale) string { return digits(r, l.PostCode) })
+}
+
+func (f *Faker) Country() string {
+	return with(f, func(r *rand.Rand, l *Locale) string { return pick(r, l.Countries) })
+}
+
+func (f *Faker) Address() string {
+	return with(f, func(r *rand.Rand, l *Locale) string {
+		return strings.NewReplacer(
+			"{number}", fmt.Sprint(1+r.IntN(999)),
+			"{street}", pick(r, l.Streets),
+			"{city}", pick(r, l.Cities),
+			"{postcode}", digits(r, l.PostCode),
+		).Replace(l.Address)
+	})
+}
+
+func (f *Faker) Company() string {
+	return with(f, func(r *rand.Rand, l *Locale) string {
+		return strings.NewReplacer("{name}", pick(r, l.Companies), "{type}", pick(r, l.CompanyTypes)).Replace(l.Company)
+	})
+}
+
+// Word is a lorem ipsum word.
+func (f *Faker) Word() string {
+	return with(f, func(r *rand.Rand, _ *Locale) string { return pick(r, words) })
+}
+
+func (f *Faker) Words(count int) []string {
+	return with(f, func(r *rand.Rand, _ *Locale) []string { return loremWords(r, count) })
+}
+
+// Sentence is 6 to 12 lorem ipsum words.
+func (f *Faker) Sentence() string {
+	return with(f, func(r *rand.Rand, _ *Locale) string { return sentence(r) })
+}
+
+// Paragraph is 3 to 6 sentences.
+func (f *Faker) Paragraph() string {
+	return with(f, func(r *rand.Rand, _ *Locale) string {
+		sentences := make([]string, 3+r.IntN(4))
+		for i := range sentences {
+			sentences[i] = sentence(r)
+		}
+		return strings.Join(sentences, " ")
+	})
+}
+
+// UUID is a version 4 UUID drawn from the seeded generator.
+func (f *Faker) UUID() string {
+	return with(f, func(r *rand.Rand, _ *Locale) string {
+		var b [16]byte
+		for i := range b {
+			b[i] = byte(r.UintN(256))
+		}
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+		return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+	})
+}
+
+// Int is a number between min and max, both included.
+func (f *Faker) Int(min, max int) int {
+	return with(f, func(r *rand.Rand, _ *Locale) int {
+		if max <= min {
+			return min
+		}
+		return min + r.IntN(max-min+1)
+	})
+}
+
+// Float is a number between min and max.
+func (f *Faker) Float(min, max float64) float64 {
+	return with(f, func(r *rand.Rand, _ *Locale) float64 { return min + r.Float64()*(max-min) })
+}
+
+func (f *Faker) Bool() bool {
+	return with(f, func(r *rand.Rand, _ *Locale) bool { return r.IntN(2) == 1 })
+}
+
+// Date is a time between from and to, truncated to the second.
+func (f *Faker) Date(from, to time.Time) time.Time {
+	return with(f, func(r *rand.Rand, _ *Locale) time.Time {
+		span := to.Unix() - from.Unix()
+		if span <= 0 {
+			return from.Truncate(time.Second)
+		}
+		return time.Unix(from.Unix()+r.Int64N(span+1), 0).In(from.Location())
+	})
+}
+
+// Past is a time within the year before the reference, Epoch by default.
+func (f *Faker) Past() time.Time {
+	ref := f.referenceTime()
+	return f.Date(ref.AddDate(-1, 0, 0), ref)
+}
+
+// Future is a time within the year after the reference, Epoch by default.
+func (f *Faker) Future() time.Time {
+	ref := f.referenceTime()
+	return f.Date(ref, ref.AddDate(1, 0, 0))
+}
+
+// helper
+
+func (f *Faker) referenceTime() time.Time {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.reference
+}
+
+func newRand(seed uint64) *rand.Rand {
+	return rand.New(rand.NewPCG(seed, seed^0x9e3779b97f4a7c15))
+}
+
+func with[V any](f *Faker, fn func(r *rand.Rand, l *Locale) V) V {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return fn(f.rand, f.locale)
+}
+
+func pick(r *rand.Rand, values []string) string {
+	return values[r.IntN(len(values))]
+}
+
+// digits replaces every `#` of the format with a random digit.
+func digits(r *rand.Rand, format string) string {
+	b := []byte(format)
+	for i, c := range b {
+		if c == '#' {
+			b[i] = byte('0' + r.IntN(10))
+		}
+	}
+	return string(b)
+}
+
+func slug(s string) string {
+	return strings.ToLower(strings.ReplaceAll(s, " ", ""))
+}
+
+func loremWords(r *rand.Rand, count int) []string {
+	result := make([]string, max(count, 0))
+	for i := range result {
+		result[i] = pick(r, words)
+	}
+	return result
+}
+
+func sentence(r *rand.Rand) string {
+	s := strings.Join(loremWords(r, 6+r.IntN(7)), " ")
+	return strings.ToUpper(s[:1]) + s[1:] + "."
+}
@@ -0,0 +1,70 @@
+package faker
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSeedIsDeterministic(t *testing.T) {
+	for _, name := range Generators() {
+		t.Run(name, func(t *testing.T) {
+			a, b := New(42), New(42)
+			for range 5 {
+				x, _ := a.Generate(name)
+				y, _ := b.Generate(name)
+				if !reflect.DeepEqual(x, y) {
+					t.Fatalf("seed 42 generated %v then %v", x, y)
+				}
+			}
+		})
+	}
+}
+
+func TestSeedRestarts(t *testing.T) {
+	f := New(7)
+	first := []any{f.Name(), f.Email(), f.Past(), f.UUID()}
+
+	f.Seed(7)
+	again := []any{f.Name(), f.Email(), f.Past(), f.UUID()}
+
+	if !reflect.DeepEqual(first, again) {
+		t.Errorf("Seed(7) generated %v, then %v", first, again)
+	}
+}
+
+func TestDatesAreRelativeToTheReference(t *testing.T) {
+	reference := time.Date(2030, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		reference *time.Time
+		generate  func(f *Faker) time.Time
+		from, to  time.Time
+	}{
+		{name: "past of the epoch", generate: (*Faker).Past, from: Epoch.AddDate(-1, 0, 0), to: Epoch},
+		{name: "future of the epoch", generate: (*Faker).Future, from: Epoch, to: Epoch.AddDate(1, 0, 0)},
+		{
+			name:     "date until the epoch",
+			generate: func(f *Faker) time.Time { v, _ := f.Generate("date"); return v.(time.Time) },
+			from:     time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC), to: Epoch,
+		},
+		{name: "past of a reference", reference: &reference, generate: (*Faker).Past, from: reference.AddDate(-1, 0, 0), to: reference},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := New(1)
+			if tt.reference != nil {
+				f.SetReference(*tt.reference)
+			}
+
+			for range 100 {
+				got := tt.generate(f)
+				if got.Before(tt.from) || got.After(tt.to) {
+					t.Fatalf("%v is not between %v and %v", got, tt.from, tt.to)
+				}
+			}
+		})
+	}
+}
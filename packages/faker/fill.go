@@ -0,0 +1,120 @@
+package faker
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"time"
+)
+
+// generators are the values a `faker` tag or a backup mask can ask for.
+var generators = map[string]func(f *Faker) any{
+	"first_name": func(f *Faker) any { return f.FirstName() },
+	"last_name":  func(f *Faker) any { return f.LastName() },
+	"name":       func(f *Faker) any { return f.Name() },
+	"username":   func(f *Faker) any { return f.Username() },
+	"email":      func(f *Faker) any { return f.Email() },
+	"phone":      func(f *Faker) any { return f.Phone() },
+	"street":     func(f *Faker) any { return f.Street() },
+	"city":       func(f *Faker) any { return f.City() },
+	"postcode":   func(f *Faker) any { return f.PostCode() },
+	"country":    func(f *Faker) any { return f.Country() },
+	"address":    func(f *Faker) any { return f.Address() },
+	"company":    func(f *Faker) any { return f.Company() },
+	"word":       func(f *Faker) any { return f.Word() },
+	"sentence":   func(f *Faker) any { return f.Sentence() },
+	"paragraph":  func(f *Faker) any { return f.Paragraph() },
+	"uuid":       func(f *Faker) any { return f.UUID() },
+	"int":        func(f *Faker) any { return f.Int(0, 1000) },
+	"float":      func(f *Faker) any { return f.Float(0, 1000) },
+	"bool":       func(f *Faker) any { return f.Bool() },
+	"date": func(f *Faker) any {
+		return f.Date(time.Date(1970, 1, 1, 0, 0, 0, 0, time.UTC), f.referenceTime())
+	},
+	"past":   func(f *Faker) any { return f.Past() },
+	"future": func(f *Faker) any { return f.Future() },
+}
+
+// Has reports whether name is a generator usable in a `faker` tag.
+func Has(name string) bool {
+	_, ok := generators[name]
+	return ok
+}
+
+// Generators returns the names usable in a `faker` tag.
+func Generators() []string {
+	return slices.Sorted(maps.Keys(generators))
+}
+
+// Generate returns a value of the named generator.
+func (f *Faker) Generate(name string) (any, error) {
+	gen, ok := generators[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown faker %q", name)
+	}
+	return gen(f), nil
+}
+
+// Fill sets the zero fields of the struct v points to from their `faker` tag, e.g. `faker:"email"`,
+// fields already set are kept. Embedded structs are filled recursively, `faker:"-"` skips a field.
+func (f *Faker) Fill(v any) error {
+	value := reflect.ValueOf(v)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("faker: Fill expects a pointer to a struct, got %T", v)
+	}
+	return f.fill(value.Elem())
+}
+
+// helper
+
+func (f *Faker) fill(v reflect.Value) error {
+	t := v.Type()
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		value := v.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+
+		tag := field.Tag.Get("faker")
+		switch {
+		case tag == "-":
+			continue
+		case tag == "":
+			// only embedded structs, a struct field of a model is an association GORM would create
+			if field.Anonymous && value.Kind() == reflect.Struct {
+				if err := f.fill(value); err != nil {
+					return err
+				}
+			}
+			continue
+		case !value.IsZero():
+			continue
+		}
+
+		generated, err := f.Generate(tag)
+		if err != nil {
+			return fmt.Errorf("faker: field %s.%s: %v", t.Name(), field.Name, err)
+		}
+
+		target := value
+		if target.Kind() == reflect.Ptr {
+			target = reflect.New(target.Type().Elem()).Elem()
+		}
+
+		g := reflect.ValueOf(generated)
+		// numbers convert to strings as runes, never mix them
+		if !g.Type().ConvertibleTo(target.Type()) || (g.Kind() == reflect.String) != (target.Kind() == reflect.String) {
+			return fmt.Errorf("faker: field %s.%s of type %s can't hold %q", t.Name(), field.Name, target.Type(), tag)
+		}
+		target.Set(g.Convert(target.Type()))
+
+		if value.Kind() == reflect.Ptr {
+			value.Set(target.Addr())
+		}
+	}
+
+	return nil
+}
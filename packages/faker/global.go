@@ -0,0 +1,48 @@
+package faker
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+// global is used by the package functions, seeded randomly until Seed is called.
+var global = New(rand.Uint64())
+
+// Seed restarts the package generator, `db:seed --seed=42` calls it so a dataset can be reproduced.
+func Seed(seed uint64) { global.Seed(seed) }
+
+// SetReference moves the time the dates of the package generator are relative to.
+func SetReference(t time.Time) { global.SetReference(t) }
+
+// SetLocale switches the locale of the package generator.
+func SetLocale(name string) error { return global.SetLocale(name) }
+
+// Default returns the package generator.
+func Default() *Faker { return global }
+
+func FirstName() string                 { return global.FirstName() }
+func LastName() string                  { return global.LastName() }
+func Name() string                      { return global.Name() }
+func Username() string                  { return global.Username() }
+func Email() string                     { return global.Email() }
+func Phone() string                     { return global.Phone() }
+func Street() string                    { return global.Street() }
+func City() string                      { return global.City() }
+func PostCode() string                  { return global.PostCode() }
+func Country() string                   { return global.Country() }
+func Address() string                   { return global.Address() }
+func Company() string                   { return global.Company() }
+func Word() string                      { return global.Word() }
+func Words(count int) []string          { return global.Words(count) }
+func Sentence() string                  { return global.Sentence() }
+func Paragraph() string                 { return global.Paragraph() }
+func UUID() string                      { return global.UUID() }
+func Int(min, max int) int              { return global.Int(min, max) }
+func Float(min, max float64) float64    { return global.Float(min, max) }
+func Bool() bool                        { return global.Bool() }
+func Date(from, to time.Time) time.Time { return global.Date(from, to) }
+func Past() time.Time                   { return global.Past() }
+func Future() time.Time                 { return global.Future() }
+
+// Fill sets the zero fields of the struct v points to from their `faker` tag with the package generator.
+func Fill(v any) error { return global.Fill(v) }
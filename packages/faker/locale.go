@@ -0,0 +1,76 @@
+package faker
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+)
+
+// Locale is the data names, addresses and phone numbers are generated from.
+// In formats `#` is a random digit, Address replaces {number}, {street}, {city} and {postcode}
+// and Company replaces {name} and {type}.
+type Locale struct {
+	FirstNames   []string
+	LastNames    []string
+	Streets      []string
+	Cities       []string
+	Countries    []string
+	Companies    []string
+	CompanyTypes []string
+	Domains      []string
+	Phone        string
+	PostCode     string
+	Address      string
+	Company      string
+}
+
+var locales = map[string]*Locale{
+	"en": {
+		FirstNames:   []string{"James", "Mary", "John", "Patricia", "Robert", "Jennifer", "Michael", "Linda", "David", "Elizabeth", "William", "Barbara", "Richard", "Susan", "Joseph", "Jessica", "Thomas", "Sarah", "Charles", "Karen", "Daniel", "Emily", "Matthew", "Olivia"},
+		LastNames:    []string{"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis", "Rodriguez", "Martinez", "Wilson", "Anderson", "Taylor", "Thomas", "Moore", "Jackson", "Martin", "Lee", "Thompson", "White"},
+		Streets:      []string{"Main St", "Oak Ave", "Maple Rd", "Cedar Ln", "Elm St", "Park Ave", "Pine St", "Washington Blvd", "Lake Dr", "Hill Rd", "Sunset Blvd", "River Rd"},
+		Cities:       []string{"Springfield", "Riverside", "Franklin", "Greenville", "Madison", "Georgetown", "Salem", "Fairview", "Clinton", "Arlington", "Ashland", "Burlington"},
+		Countries:    []string{"United States", "Canada", "United Kingdom", "Australia", "New Zealand", "Ireland"},
+		Companies:    []string{"Acme", "Globex", "Initech", "Umbrella", "Hooli", "Stark", "Wayne", "Wonka", "Soylent", "Vandelay"},
+		CompanyTypes: []string{"Inc", "LLC", "Ltd", "Group", "Corp"},
+		Domains:      []string{"example.com", "example.net", "example.org"},
+		Phone:        "+1 (###) ###-####",
+		PostCode:     "#####",
+		Address:      "{number} {street}, {city} {postcode}",
+		Company:      "{name} {type}",
+	},
+	"id": {
+		FirstNames:   []string{"Budi", "Siti", "Agus", "Dewi", "Rizky", "Putri", "Andi", "Ayu", "Hendra", "Rina", "Joko", "Sri", "Fajar", "Intan", "Eko", "Nur", "Bayu", "Wulan", "Dimas", "Indah"},
+		LastNames:    []string{"Santoso", "Wijaya", "Pratama", "Saputra", "Hidayat", "Lestari", "Kurniawan", "Setiawan", "Nugroho", "Susanto", "Wibowo", "Gunawan", "Siregar", "Halim", "Utami", "Purnama"},
+		Streets:      []string{"Jl. Sudirman", "Jl. Thamrin", "Jl. Merdeka", "Jl. Gatot Subroto", "Jl. Diponegoro", "Jl. Ahmad Yani", "Jl. Pahlawan", "Jl. Gajah Mada", "Jl. Asia Afrika", "Jl. Veteran"},
+		Cities:       []string{"Jakarta", "Bandung", "Surabaya", "Medan", "Semarang", "Yogyakarta", "Makassar", "Denpasar", "Palembang", "Malang", "Bogor", "Balikpapan"},
+		Countries:    []string{"Indonesia"},
+		Companies:    []string{"Maju Jaya", "Sinar Abadi", "Karya Mandiri", "Sumber Makmur", "Cahaya Baru", "Mitra Sejahtera", "Nusantara", "Bintang Timur"},
+		CompanyTypes: []string{"PT", "CV"},
+		Domains:      []string{"example.com", "example.net", "example.org"},
+		Phone:        "+62 8##-####-####",
+		PostCode:     "#####",
+		Address:      "{street} No. {number}, {city} {postcode}",
+		Company:      "{type} {name}",
+	},
+}
+
+// RegisterLocale adds or replaces a locale, use it before seeding.
+func RegisterLocale(name string, locale *Locale) {
+	locales[name] = locale
+}
+
+// Locales returns the names of the registered locales.
+func Locales() []string {
+	return slices.Sorted(maps.Keys(locales))
+}
+
+// helper
+
+func locale(name string) (*Locale, error) {
+	l, ok := locales[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown locale %q, expected one of %v", name, Locales())
+	}
+	return l, nil
+}
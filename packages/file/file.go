@@ -1,14 +1,53 @@
 package file
 
 import (
+	"bytes"
 	"fmt"
+	"go/format"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"strings"
+	"text/template"
+
+	"github.com/jinzhu/inflection"
+	c "webservices/packages/common"
 )
 
+// Funcs are the helpers templates can use, e.g. {{.Name | snake | plural}}.
+var Funcs = template.FuncMap{
+	"camel":    c.ToCamelCase,
+	"pascal":   func(s string) string { return c.ToUpper(c.ToCamelCase(s)) },
+	"snake":    c.ToSnakeCase,
+	"kebab":    c.ToKebabCase,
+	"plural":   inflection.Plural,
+	"singular": inflection.Singular,
+	"lower":    strings.ToLower,
+	"upper":    strings.ToUpper,
+}
+
+// Create renders the text/template source with data into filePath, Go files are gofmt'ed.
 func Create(filePath, source string, data *map[string]any) {
+	result, err := Render(filepath.Base(filePath), source, data)
+	if err != nil {
+		slog.Error("render template",
+			slog.String("path", filePath),
+			slog.Any("error", err))
+		return
+	}
+
+	if filepath.Ext(filePath) == ".go" {
+		formatted, err := format.Source(result)
+		if err != nil {
+			// still written, so the broken output can be inspected
+			slog.Error("format file",
+				slog.String("path", filePath),
+				slog.Any("error", err))
+		} else {
+			result = formatted
+		}
+	}
+
 	dir := filepath.Dir(filePath)
 	if err := os.MkdirAll(dir, 0755); err != nil {
 		slog.Error("create directory",
@@ -26,16 +65,7 @@ func Create(filePath, source string, data *map[string]any) {
 	}
 	defer file.Close()
 
-	result := source
-
-	if data != nil {
-		for key, value := range *data {
-			placeholder := "{{." + key + "}}"
-			result = strings.ReplaceAll(result, placeholder, fmt.Sprintf("%v", value))
-		}
-	}
-
-	if _, err := file.WriteString(result); err != nil {
+	if _, err := file.Write(result); err != nil {
 		slog.Error("write file",
 			slog.String("path", filePath),
 			slog.Any("error", err))
@@ -44,3 +74,22 @@ func Create(filePath, source string, data *map[string]any) {
 
 	fmt.Printf("created: %s\n", filePath)
 }
+
+// Render executes the text/template source with data, a missing key is an error.
+func Render(name, source string, data *map[string]any) ([]byte, error) {
+	tmpl, err := template.New(name).Funcs(Funcs).Option("missingkey=error").Parse(source)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse template: %v", err)
+	}
+
+	values := map[string]any{}
+	if data != nil {
+		values = *data
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, values); err != nil {
+		return nil, fmt.Errorf("failed to execute template: %v", err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,105 @@
+package file
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name    string
+		source  string
+		data    map[string]any
+		want    string
+		wantErr string
+	}{
+		{name: "camel", source: "{{.Name | camel}}", data: map[string]any{"Name": "order_item"}, want: "orderItem"},
+		{name: "pascal", source: "{{.Name | pascal}}", data: map[string]any{"Name": "order_item"}, want: "OrderItem"},
+		{name: "snake", source: "{{.Name | snake}}", data: map[string]any{"Name": "OrderItem"}, want: "order_item"},
+		{name: "kebab", source: "{{.Name | kebab}}", data: map[string]any{"Name": "OrderItem"}, want: "order-item"},
+		{name: "plural", source: "{{.Name | plural}}", data: map[string]any{"Name": "category"}, want: "categories"},
+		{name: "singular", source: "{{.Name | singular}}", data: map[string]any{"Name": "people"}, want: "person"},
+		{name: "lower", source: "{{.Name | lower}}", data: map[string]any{"Name": "User"}, want: "user"},
+		{name: "upper", source: "{{.Name | upper}}", data: map[string]any{"Name": "User"}, want: "USER"},
+		{name: "chained", source: "{{.Name | snake | plural}}", data: map[string]any{"Name": "OrderItem"}, want: "order_items"},
+		{name: "no data", source: "package model", want: "package model"},
+		{name: "missing key", source: "{{.Name}} {{.Table}}", data: map[string]any{"Name": "User"}, wantErr: `map has no entry for key "Table"`},
+		{name: "unknown helper", source: "{{.Name | title}}", data: map[string]any{"Name": "user"}, wantErr: `function "title" not defined`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data *map[string]any
+			if tt.data != nil {
+				data = &tt.data
+			}
+
+			got, err := Render(tt.name, tt.source, data)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("rendered %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCreate(t *testing.T) {
+	tests := []struct {
+		name   string
+		file   string
+		source string
+		want   string
+	}{
+		{
+			name:   "go files are formatted",
+			file:   "model/user.go",
+			source: "package model\ntype {{.Name}} struct{ID int}\n",
+			want:   "package model\n\ntype User struct{ ID int }\n",
+		},
+		{
+			name:   "go files failing gofmt are written as rendered",
+			file:   "model/user.go",
+			source: "package model\ntype {{.Name}} struct{\n",
+			want:   "package model\ntype User struct{\n",
+		},
+		{
+			name:   "other files are not formatted",
+			file:   "migrations/create_users.up.sql",
+			source: "CREATE TABLE {{.Name | snake | plural}}  (id int);\n",
+			want:   "CREATE TABLE users  (id int);\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), tt.file)
+			Create(path, tt.source, &map[string]any{"Name": "User"})
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("nothing written when rendering fails", func(t *testing.T) {
+		path := filepath.Join(t.TempDir(), "user.go")
+		Create(path, "package {{.Package}}", &map[string]any{"Name": "User"})
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("stat = %v, want no file", err)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strconv"
+	"strings"
+
+	c "webservices/packages/common"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+)
+
+// S3PartSize is the size of the parts of multipart uploads, the memory an upload buffers.
+const S3PartSize = 16 << 20
+
+// S3 stores files in a bucket of an S3 compatible object storage, AWS S3, MinIO, R2 and the like.
+type S3 struct {
+	client *minio.Client
+	bucket string
+	prefix string
+}
+
+// NewS3 connects to the storage configured by S3_ENDPOINT, S3_ACCESS_KEY, S3_SECRET_KEY,
+// S3_REGION and S3_USE_SSL, the endpoint may also be a URL like `http://localhost:9000`.
+func NewS3(bucket, prefix string) (*S3, error) {
+	endpoint := c.Env("S3_ENDPOINT", "s3.amazonaws.com")
+	secure, err := strconv.ParseBool(c.Env("S3_USE_SSL", "true"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid S3_USE_SSL: %v", err)
+	}
+
+	if strings.Contains(endpoint, "://") {
+		u, err := url.Parse(endpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid S3_ENDPOINT: %v", err)
+		}
+		endpoint, secure = u.Host, u.Scheme == "https"
+	}
+
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(c.Env("S3_ACCESS_KEY"), c.Env("S3_SECRET_KEY"), ""),
+		Secure: secure,
+		Region: c.Env("S3_REGION"),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create S3 client: %v", err)
+	}
+
+	return &S3{client: client, bucket: bucket, prefix: prefix}, nil
+}
+
+// Put streams r with a multipart upload, the size doesn't need to be known upfront.
+func (s *S3) Put(ctx context.Context, name string, r io.Reader) error {
+	_, err := s.client.PutObject(ctx, s.bucket, s.key(name), r, -1, minio.PutObjectOptions{
+		PartSize:    S3PartSize,
+		ContentType: "application/octet-stream",
+	})
+	return err
+}
+
+func (s *S3) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	object, err := s.client.GetObject(ctx, s.bucket, s.key(name), minio.GetObjectOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	// errors only surface on the first read, a missing object is reported here instead
+	if _, err := object.Stat(); err != nil {
+		object.Close()
+		return nil, err
+	}
+	return object, nil
+}
+
+func (s *S3) List(ctx context.Context, prefix string) ([]string, error) {
+	base := ""
+	if s.prefix != "" {
+		base = s.prefix + "/"
+	}
+
+	var names []string
+	for object := range s.client.ListObjects(ctx, s.bucket, minio.ListObjectsOptions{
+		Prefix:    base + prefix,
+		Recursive: true,
+	}) {
+		if object.Err != nil {
+			return nil, object.Err
+		}
+		names = append(names, strings.TrimPrefix(object.Key, base))
+	}
+	return names, nil
+}
+
+func (s *S3) String() string {
+	return "s3://" + path.Join(s.bucket, s.prefix)
+}
+
+// helper
+
+func (s *S3) key(name string) string {
+	return path.Join(s.prefix, name)
+}
@@ -0,0 +1,180 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// Destination stores backup files by slash separated names,
+// relative to the directory or bucket prefix it was opened with.
+type Destination interface {
+	// Put streams r to name, replacing it when it exists.
+	Put(ctx context.Context, name string, r io.Reader) error
+	// Get opens name for reading.
+	Get(ctx context.Context, name string) (io.ReadCloser, error)
+	// List returns the names starting with prefix, including nested ones.
+	List(ctx context.Context, prefix string) ([]string, error)
+	String() string
+}
+
+// IsRemote reports whether uri has to be opened with Open, plain paths are used as is.
+func IsRemote(uri string) bool {
+	return strings.HasPrefix(uri, "s3://") || strings.HasPrefix(uri, "file://")
+}
+
+// Open returns the destination of `s3://bucket/prefix` or `file:///path`, a mounted directory.
+func Open(uri string) (Destination, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return nil, fmt.Errorf("invalid destination %q: %v", uri, err)
+	}
+
+	switch u.Scheme {
+	case "s3":
+		if u.Host == "" {
+			return nil, fmt.Errorf("invalid destination %q: missing bucket", uri)
+		}
+		return NewS3(u.Host, strings.Trim(u.Path, "/"))
+	case "file", "":
+		dir := u.Path
+		if u.Host != "" {
+			// file://relative/path
+			dir = u.Host + u.Path
+		}
+		if dir == "" {
+			return nil, fmt.Errorf("invalid destination %q: missing path", uri)
+		}
+		return NewLocal(filepath.FromSlash(dir)), nil
+	default:
+		return nil, fmt.Errorf("unsupported destination %q, expected s3:// or file://", uri)
+	}
+}
+
+// Upload puts the files of dir into the destination under prefix, subdirectories and dot files are skipped.
+func Upload(ctx context.Context, dest Destination, dir, prefix string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %v", dir, err)
+	}
+
+	var uploaded []string
+	for _, entry := range entries {
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			continue
+		}
+
+		name := path.Join(prefix, entry.Name())
+		if err := putFile(ctx, dest, filepath.Join(dir, entry.Name()), name); err != nil {
+			return uploaded, fmt.Errorf("failed to upload %s to %s: %v", entry.Name(), dest, err)
+		}
+		uploaded = append(uploaded, name)
+	}
+
+	return uploaded, nil
+}
+
+// Fetch downloads the file or folder uri points to into dir and returns its local path.
+// Buckets are never fetched whole, uri needs a prefix like `s3://bucket/prefix`.
+func Fetch(ctx context.Context, uri, dir string) (string, error) {
+	trimmed := strings.TrimRight(uri, "/")
+	i := strings.LastIndex(trimmed, "/")
+	parent, base := trimmed[:i], trimmed[i+1:]
+
+	// a mount root is fetched as a folder
+	if strings.HasSuffix(parent, ":/") || strings.HasSuffix(parent, "://") {
+		if strings.HasPrefix(uri, "s3://") {
+			return "", fmt.Errorf("invalid input %q: missing prefix, e.g. s3://bucket/prefix/20060102", uri)
+		}
+		parent, base = trimmed, ""
+	}
+
+	dest, err := Open(parent)
+	if err != nil {
+		return "", err
+	}
+
+	target, err := fetch(ctx, dest, base, dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to fetch %s: %v", uri, err)
+	}
+	return target, nil
+}
+
+// helper
+
+// fetch downloads base, a name or a folder of dest, into dir. Names are only written inside of dir.
+func fetch(ctx context.Context, dest Destination, base, dir string) (string, error) {
+	if base != "" && !filepath.IsLocal(base) {
+		return "", fmt.Errorf("invalid name %q", base)
+	}
+
+	names, err := dest.List(ctx, base)
+	if err != nil {
+		return "", fmt.Errorf("failed to list %s: %v", dest, err)
+	}
+
+	target := filepath.Join(dir, base)
+	found := false
+
+	for _, name := range names {
+		switch {
+		case name == base:
+			// the uri is a single file, an archive
+			return target, getFile(ctx, dest, name, target)
+		case base == "" || strings.HasPrefix(name, base+"/"):
+			rel := filepath.FromSlash(strings.TrimPrefix(strings.TrimPrefix(name, base), "/"))
+			// keys like `prefix/../../etc/cron.d/x` would escape dir
+			if !filepath.IsLocal(rel) {
+				return "", fmt.Errorf("refusing to download %s outside of %s", name, target)
+			}
+			if err := getFile(ctx, dest, name, filepath.Join(target, rel)); err != nil {
+				return "", err
+			}
+			found = true
+		}
+	}
+
+	if !found {
+		return "", fmt.Errorf("nothing found")
+	}
+	return target, nil
+}
+
+func putFile(ctx context.Context, dest Destination, src, name string) error {
+	file, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	return dest.Put(ctx, name, file)
+}
+
+func getFile(ctx context.Context, dest Destination, name, target string) error {
+	r, err := dest.Get(ctx, name)
+	if err != nil {
+		return fmt.Errorf("failed to download %s: %v", name, err)
+	}
+	defer r.Close()
+
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return err
+	}
+
+	file, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return fmt.Errorf("failed to download %s: %v", name, err)
+	}
+	return file.Close()
+}
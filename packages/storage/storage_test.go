@@ -0,0 +1,122 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// memory is a destination holding files in a map, names may be anything a bucket accepts.
+type memory map[string]string
+
+func (m memory) Put(ctx context.Context, name string, r io.Reader) error {
+	data, err := io.ReadAll(r)
+	m[name] = string(data)
+	return err
+}
+
+func (m memory) Get(ctx context.Context, name string) (io.ReadCloser, error) {
+	return io.NopCloser(strings.NewReader(m[name])), nil
+}
+
+func (m memory) List(ctx context.Context, prefix string) ([]string, error) {
+	var names []string
+	for name := range m {
+		if strings.HasPrefix(name, prefix) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names, nil
+}
+
+func (m memory) String() string { return "memory" }
+
+func TestFetch(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   memory
+		base    string
+		want    []string // files written, relative to dir
+		wantErr string
+	}{
+		{
+			name:  "folder",
+			files: memory{"20250518/a.json": "a", "20250518/b.json": "b", "20250519/c.json": "c"},
+			base:  "20250518",
+			want:  []string{"20250518/a.json", "20250518/b.json"},
+		},
+		{
+			name:  "single archive",
+			files: memory{"20250518/backup_20250518013000.tar.zst": "archive"},
+			base:  "20250518/backup_20250518013000.tar.zst",
+			want:  []string{"20250518/backup_20250518013000.tar.zst"},
+		},
+		{
+			name:    "key escaping the folder",
+			files:   memory{"20250518/../../escaped": "x"},
+			base:    "20250518",
+			wantErr: "refusing to download",
+		},
+		{
+			name:  "absolute key stays inside",
+			files: memory{"/etc/inside": "x"},
+			base:  "",
+			want:  []string{"etc/inside"},
+		},
+		{
+			name:    "base escaping dir",
+			files:   memory{"../escaped": "x"},
+			base:    "..",
+			wantErr: "invalid name",
+		},
+		{
+			name:    "nothing found",
+			files:   memory{"20250519/c.json": "c"},
+			base:    "20250518",
+			wantErr: "nothing found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+
+			_, err := fetch(context.Background(), tt.files, tt.base, dir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+
+			got := []string{}
+			filepath.WalkDir(filepath.Dir(dir), func(path string, d os.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					rel, _ := filepath.Rel(dir, path)
+					got = append(got, filepath.ToSlash(rel))
+				}
+				return nil
+			})
+			if tt.want == nil {
+				tt.want = []string{}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("wrote %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFetchRequiresBucketPrefix(t *testing.T) {
+	for _, uri := range []string{"s3://bucket", "s3://bucket/"} {
+		if _, err := Fetch(context.Background(), uri, t.TempDir()); err == nil || !strings.Contains(err.Error(), "missing prefix") {
+			t.Errorf("Fetch(%q) error = %v, want a missing prefix error", uri, err)
+		}
+	}
+}
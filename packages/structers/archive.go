@@ -0,0 +1,312 @@
+package structers
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"syscall"
+	cipher "webservices/packages/chiper"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// archive extension by compression, encrypted archives get an extra `.enc` suffix
+var archiveExtensions = map[string]string{
+	"gzip": "tar.gz",
+	"zstd": "tar.zst",
+}
+
+const encryptedExtension = ".enc"
+
+// archiveName returns `backup_<timestamp>.<ext>` of a compressed, optionally encrypted, archive.
+func archiveName(timestamp, compress string, encrypt bool) (string, error) {
+	ext, ok := archiveExtensions[compress]
+	if !ok {
+		return "", fmt.Errorf("unknown compression %q, expected gzip or zstd", compress)
+	}
+
+	name := fmt.Sprintf("backup_%s.%s", timestamp, ext)
+	if encrypt {
+		name += encryptedExtension
+	}
+	return name, nil
+}
+
+// isArchive reports whether name is a backup archive written by writeArchive.
+func isArchive(name string) bool {
+	name = strings.TrimSuffix(name, encryptedExtension)
+	for _, ext := range archiveExtensions {
+		if strings.HasPrefix(name, "backup_") && strings.HasSuffix(name, "."+ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeArchive tars the files into dest, compressed and encrypted with key when given.
+func writeArchive(dest string, files []string, compress string, key []byte) (err error) {
+	file, err := os.Create(dest)
+	if err != nil {
+		return fmt.Errorf("failed to create archive %s: %v", dest, err)
+	}
+	defer func() {
+		if cerr := file.Close(); err == nil {
+			err = cerr
+		}
+		if err != nil {
+			os.Remove(dest)
+		}
+	}()
+
+	var w io.Writer = file
+	var closers []io.Closer
+
+	if key != nil {
+		enc, err := cipher.NewEncryptWriter(key, w)
+		if err != nil {
+			return fmt.Errorf("failed to encrypt archive: %v", err)
+		}
+		w = enc
+		closers = append(closers, enc)
+	}
+
+	switch compress {
+	case "gzip":
+		gz := gzip.NewWriter(w)
+		w = gz
+		closers = append(closers, gz)
+	case "zstd":
+		zw, err := zstd.NewWriter(w)
+		if err != nil {
+			return err
+		}
+		w = zw
+		closers = append(closers, zw)
+	default:
+		return fmt.Errorf("unknown compression %q, expected gzip or zstd", compress)
+	}
+
+	tw := tar.NewWriter(w)
+	closers = append(closers, tw)
+
+	for _, path := range files {
+		if err := addToArchive(tw, path); err != nil {
+			return fmt.Errorf("failed to archive %s: %v", path, err)
+		}
+	}
+
+	// close from the tar writer down to the file
+	slices.Reverse(closers)
+	for _, c := range closers {
+		if err := c.Close(); err != nil {
+			return fmt.Errorf("failed to write archive %s: %v", dest, err)
+		}
+	}
+
+	return nil
+}
+
+// extractArchive decrypts and decompresses an archive into dir.
+func extractArchive(path string, key []byte, dir string) error {
+	tr, closeArchive, err := openArchive(path, key)
+	if err != nil {
+		return err
+	}
+	defer closeArchive()
+
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to read archive %s: %v", path, err)
+		}
+
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+
+		// archives are flat, never write outside of dir
+		target := filepath.Join(dir, filepath.Base(header.Name))
+		if err := extractFile(tr, target); err != nil {
+			return fmt.Errorf("failed to extract %s: %v", header.Name, err)
+		}
+	}
+}
+
+// readArchiveManifest reads the manifest of an archive, which is its first entry, nil when there is none.
+func readArchiveManifest(path string, key []byte) (*BackupManifest, error) {
+	tr, closeArchive, err := openArchive(path, key)
+	if err != nil {
+		return nil, err
+	}
+	defer closeArchive()
+
+	header, err := tr.Next()
+	if err == io.EOF || (err == nil && header.Name != manifestName) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read archive %s: %v", path, err)
+	}
+
+	var manifest BackupManifest
+	if err := json.NewDecoder(tr).Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest of %s: %v", path, err)
+	}
+	return &manifest, nil
+}
+
+// latestArchive returns the newest backup archive in dir, or an empty string.
+func latestArchive(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read backup directory: %v", err)
+	}
+
+	var latest string
+	for _, entry := range entries {
+		// names only differ by the fixed width timestamp
+		if !entry.IsDir() && isArchive(entry.Name()) && entry.Name() > latest {
+			latest = entry.Name()
+		}
+	}
+
+	if latest == "" {
+		return "", nil
+	}
+	return filepath.Join(dir, latest), nil
+}
+
+// openArchive returns a tar reader over the decrypted and decompressed archive, based on its extension.
+func openArchive(path string, key []byte) (*tar.Reader, func(), error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open archive %s: %v", path, err)
+	}
+
+	name := filepath.Base(path)
+	var r io.Reader = file
+	closers := []func(){func() { file.Close() }}
+	closeAll := func() {
+		for i := len(closers) - 1; i >= 0; i-- {
+			closers[i]()
+		}
+	}
+
+	if strings.HasSuffix(name, encryptedExtension) {
+		if key == nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("archive %s is encrypted, set KEY to restore it", name)
+		}
+		if r, err = cipher.NewDecryptReader(key, r); err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("failed to decrypt archive %s: %v", name, err)
+		}
+		name = strings.TrimSuffix(name, encryptedExtension)
+	}
+
+	switch {
+	case strings.HasSuffix(name, "."+archiveExtensions["gzip"]):
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("failed to decompress archive %s: %v", path, err)
+		}
+		r = gz
+		closers = append(closers, func() { gz.Close() })
+	case strings.HasSuffix(name, "."+archiveExtensions["zstd"]):
+		zr, err := zstd.NewReader(r)
+		if err != nil {
+			closeAll()
+			return nil, nil, fmt.Errorf("failed to decompress archive %s: %v", path, err)
+		}
+		r = zr
+		closers = append(closers, zr.Close)
+	default:
+		closeAll()
+		return nil, nil, fmt.Errorf("unknown archive %s", path)
+	}
+
+	return tar.NewReader(r), closeAll, nil
+}
+
+// privateTempDir creates a 0700 temporary directory for plaintext backup files, outside of the backup output.
+// The returned cleanup removes it, an interrupt or termination signal removes it before the process exits.
+func privateTempDir(prefix string) (string, func(), error) {
+	dir, err := os.MkdirTemp("", prefix)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to create temporary directory: %v", err)
+	}
+
+	signals := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+
+	go func() {
+		select {
+		case sig := <-signals:
+			os.RemoveAll(dir)
+			// exit the way the signal would have without the handler
+			signal.Stop(signals)
+			if p, err := os.FindProcess(os.Getpid()); err == nil {
+				p.Signal(sig)
+			}
+		case <-done:
+		}
+	}()
+
+	cleanup := sync.OnceFunc(func() {
+		signal.Stop(signals)
+		close(done)
+		os.RemoveAll(dir)
+	})
+	return dir, cleanup, nil
+}
+
+func addToArchive(tw *tar.Writer, path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
+	header, err := tar.FileInfoHeader(info, "")
+	if err != nil {
+		return err
+	}
+	header.Name = filepath.Base(path)
+
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+	_, err = io.Copy(tw, file)
+	return err
+}
+
+func extractFile(r io.Reader, target string) error {
+	file, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.Copy(file, r); err != nil {
+		file.Close()
+		return err
+	}
+	return file.Close()
+}
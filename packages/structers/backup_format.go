@@ -0,0 +1,273 @@
+package structers
+
+import (
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BackupWriter writes the rows of one table in a backup format.
+type BackupWriter interface {
+	// Begin is called once with the table columns before the first row.
+	Begin(table string, columns []string) error
+	// Write is called for every row, keyed by column name.
+	Write(row map[string]any) error
+	// End is called after the last row, also for empty tables.
+	End() error
+}
+
+// BackupReader reads back the rows of a file written by a BackupWriter, keyed as written.
+type BackupReader func(r io.Reader, fn func(map[string]any) error) error
+
+// BackupFormat is a backup file format, register your own in `Database.BackupFormats`.
+type BackupFormat struct {
+	Extension string
+	NewWriter func(w io.Writer, opts BackupOptions) BackupWriter
+	// Reader is optional, files without a reader can't be restored by db:restore
+	Reader BackupReader
+}
+
+var backupFormats = map[string]BackupFormat{
+	"json": {
+		Extension: "json",
+		NewWriter: func(w io.Writer, _ BackupOptions) BackupWriter { return &jsonWriter{w: w} },
+		Reader:    readJSONArray,
+	},
+	"ndjson": {
+		Extension: "ndjson",
+		NewWriter: func(w io.Writer, _ BackupOptions) BackupWriter { return &ndjsonWriter{enc: json.NewEncoder(w)} },
+		Reader:    readNDJSON,
+	},
+	"csv": {
+		Extension: "csv",
+		NewWriter: func(w io.Writer, _ BackupOptions) BackupWriter { return &csvWriter{w: csv.NewWriter(w)} },
+	},
+	"sql": {
+		Extension: "sql",
+		NewWriter: func(w io.Writer, opts BackupOptions) BackupWriter { return &sqlWriter{w: w, copy: opts.SQLCopy} },
+	},
+}
+
+// BackupFormat returns the registered format by name, the registry formats take precedence over the built-in ones.
+func (r *DatabaseRegistry) BackupFormat(name string) (BackupFormat, error) {
+	if f, ok := r.BackupFormats[name]; ok {
+		return f, nil
+	}
+	if f, ok := backupFormats[name]; ok {
+		return f, nil
+	}
+	return BackupFormat{}, fmt.Errorf("unknown backup format %q", name)
+}
+
+// formatByExtension finds the format writing files with the given extension.
+func (r *DatabaseRegistry) formatByExtension(ext string) (BackupFormat, bool) {
+	for _, formats := range []map[string]BackupFormat{r.BackupFormats, backupFormats} {
+		for _, f := range formats {
+			if f.Extension == ext {
+				return f, true
+			}
+		}
+	}
+	return BackupFormat{}, false
+}
+
+// json, an indented array of camelCase keyed objects
+
+type jsonWriter struct {
+	w     io.Writer
+	count int
+}
+
+func (j *jsonWriter) Begin(string, []string) error {
+	_, err := io.WriteString(j.w, "[")
+	return err
+}
+
+func (j *jsonWriter) Write(row map[string]any) error {
+	data, err := json.MarshalIndent(camelCaseRow(row), "  ", "  ")
+	if err != nil {
+		return err
+	}
+
+	sep := ",\n  "
+	if j.count == 0 {
+		sep = "\n  "
+	}
+	j.count++
+
+	if _, err := io.WriteString(j.w, sep); err != nil {
+		return err
+	}
+	_, err = j.w.Write(data)
+	return err
+}
+
+func (j *jsonWriter) End() error {
+	_, err := io.WriteString(j.w, "\n]\n")
+	return err
+}
+
+// ndjson, one camelCase keyed object per line
+
+type ndjsonWriter struct {
+	enc *json.Encoder
+}
+
+func (n *ndjsonWriter) Begin(string, []string) error { return nil }
+
+func (n *ndjsonWriter) Write(row map[string]any) error {
+	return n.enc.Encode(camelCaseRow(row))
+}
+
+func (n *ndjsonWriter) End() error { return nil }
+
+// csv, a header row of column names, NULL is written as an empty field
+
+type csvWriter struct {
+	w       *csv.Writer
+	columns []string
+}
+
+func (c *csvWriter) Begin(_ string, columns []string) error {
+	c.columns = columns
+	return c.w.Write(columns)
+}
+
+func (c *csvWriter) Write(row map[string]any) error {
+	record := make([]string, len(c.columns))
+	for i, column := range c.columns {
+		record[i] = textValue(row[column])
+	}
+	return c.w.Write(record)
+}
+
+func (c *csvWriter) End() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// sql, INSERT statements or a COPY block which can be replayed with psql
+
+type sqlWriter struct {
+	w       io.Writer
+	copy    bool
+	table   string
+	columns []string
+}
+
+func (s *sqlWriter) Begin(table string, columns []string) error {
+	s.table = quoteIdent(table)
+	s.columns = columns
+
+	if !s.copy {
+		return nil
+	}
+
+	_, err := fmt.Fprintf(s.w, "COPY %s (%s) FROM stdin;\n", s.table, s.columnList())
+	return err
+}
+
+func (s *sqlWriter) Write(row map[string]any) error {
+	values := make([]string, len(s.columns))
+
+	if s.copy {
+		for i, column := range s.columns {
+			values[i] = copyValue(row[column])
+		}
+		_, err := fmt.Fprintln(s.w, strings.Join(values, "\t"))
+		return err
+	}
+
+	for i, column := range s.columns {
+		values[i] = sqlLiteral(row[column])
+	}
+	_, err := fmt.Fprintf(s.w, "INSERT INTO %s (%s) VALUES (%s);\n", s.table, s.columnList(), strings.Join(values, ", "))
+	return err
+}
+
+func (s *sqlWriter) End() error {
+	if !s.copy {
+		return nil
+	}
+	_, err := io.WriteString(s.w, "\\.\n")
+	return err
+}
+
+func (s *sqlWriter) columnList() string {
+	quoted := make([]string, len(s.columns))
+	for i, c := range s.columns {
+		quoted[i] = quoteIdent(c)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// readers
+
+func readNDJSON(r io.Reader, fn func(map[string]any) error) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	for dec.More() {
+		var record map[string]any
+		if err := dec.Decode(&record); err != nil {
+			return err
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// value encoding
+
+// textValue is the postgres text representation of a scanned value.
+func textValue(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []byte:
+		return `\x` + hex.EncodeToString(v)
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case bool:
+		return strconv.FormatBool(v)
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprint(v)
+		}
+		return string(data)
+	}
+}
+
+func sqlLiteral(value any) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case bool:
+		return strings.ToUpper(strconv.FormatBool(v))
+	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64, float32, float64:
+		return fmt.Sprint(v)
+	default:
+		return quoteLiteral(textValue(v))
+	}
+}
+
+var copyEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`)
+
+func copyValue(value any) string {
+	if value == nil {
+		return `\N`
+	}
+	return copyEscaper.Replace(textValue(value))
+}
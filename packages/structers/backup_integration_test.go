@@ -0,0 +1,188 @@
+package structers
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackupRestoreSchemaQualifiedTable(t *testing.T) {
+	db := testDB(t)
+
+	var schema string
+	if err := db.Raw("SELECT current_schema()").Scan(&schema).Error; err != nil {
+		t.Fatal(err)
+	}
+	table := schema + ".users"
+
+	err := db.Exec(`CREATE TABLE users (id serial PRIMARY KEY, name text, avatar bytea);
+		CREATE TABLE orders (id int PRIMARY KEY, user_id int REFERENCES users);
+		INSERT INTO users (name, avatar) VALUES ('ada', '\xdeadbeef'), ('alan', NULL)`).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	columns, err := tableColumnNames(db, table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"id", "name", "avatar"}; !slices.Equal(columns, want) {
+		t.Fatalf("columns of %s = %v, want %v", table, columns, want)
+	}
+	if referencing, err := referencingTables(db, []string{table}); err != nil || !slices.Equal(referencing, []string{"orders"}) {
+		t.Fatalf("tables referencing %s = %v, %v, want [orders]", table, referencing, err)
+	}
+
+	output := t.TempDir()
+	registry := &DatabaseRegistry{Tables: []string{table}}
+	if err := registry.Backup(db, BackupOptions{Output: output}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.Exec("DELETE FROM users").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Restore(db, RestoreOptions{Input: output}); err != nil {
+		t.Fatal(err)
+	}
+
+	var users []struct {
+		ID     int
+		Name   string
+		Avatar []byte
+	}
+	if err := db.Raw("SELECT id, name, avatar FROM users ORDER BY id").Scan(&users).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(users) != 2 || users[0].Name != "ada" || string(users[0].Avatar) != "\xde\xad\xbe\xef" || users[1].Avatar != nil {
+		t.Fatalf("restored users = %+v", users)
+	}
+
+	// the sequence moved past the restored ids
+	var next int
+	if err := db.Raw("INSERT INTO users (name) VALUES ('grace') RETURNING id").Scan(&next).Error; err != nil {
+		t.Fatal(err)
+	}
+	if next != 3 {
+		t.Errorf("next id = %d, want 3", next)
+	}
+}
+
+func TestBackupManifest(t *testing.T) {
+	db := testDB(t)
+
+	err := db.Exec(`CREATE TABLE users (id bigserial PRIMARY KEY, email varchar(64) NOT NULL, visits int DEFAULT 0);
+		INSERT INTO users (email) VALUES ('ada@example.com'), ('alan@example.com')`).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := t.TempDir()
+	registry := &DatabaseRegistry{Tables: []string{"users"}}
+	if err := registry.Backup(db, BackupOptions{Output: output, Format: "ndjson", Version: "1.0.0"}); err != nil {
+		t.Fatal(err)
+	}
+
+	manifest, err := ReadManifest(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Type != BackupFull || manifest.Format != "ndjson" || manifest.Version != "1.0.0" || len(manifest.Tables) != 1 {
+		t.Fatalf("manifest = %+v", manifest)
+	}
+
+	table := manifest.Tables[0]
+	checksum, err := fileChecksum(filepath.Join(output, table.File))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if table.Rows != 2 || table.SHA256 != checksum {
+		t.Errorf("table = %d rows with checksum %s, want 2 rows with checksum %s", table.Rows, table.SHA256, checksum)
+	}
+
+	var names []string
+	for _, column := range table.Columns {
+		names = append(names, column.Name)
+	}
+	if want := []string{"id", "email", "visits"}; !slices.Equal(names, want) {
+		t.Fatalf("columns = %v, want %v", names, want)
+	}
+	if id := table.Columns[0]; !id.PrimaryKey || id.Nullable {
+		t.Errorf("id column = %+v, want a not null primary key", id)
+	}
+	if email := table.Columns[1]; !strings.Contains(email.Type, "64") || email.Nullable {
+		t.Errorf("email column = %+v, want a not null varchar(64)", email)
+	}
+	if visits := table.Columns[2]; !visits.Nullable || visits.Default == nil {
+		t.Errorf("visits column = %+v, want a nullable column with a default", visits)
+	}
+
+	_, results, err := registry.VerifyBackup(output, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || !results[0].OK() || results[0].Rows != 2 {
+		t.Fatalf("verification = %+v, want the table verified", results)
+	}
+
+	// a changed file fails the verification
+	file, err := os.OpenFile(filepath.Join(output, table.File), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"id": 3, "email": "grace@example.com"}` + "\n")
+	file.Close()
+
+	_, results, err = registry.VerifyBackup(output, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"checksum mismatch", "expected 2 rows, found 3"}; len(results) != 1 || !slices.Equal(results[0].Problems, want) {
+		t.Errorf("verification = %+v, want %v", results, want)
+	}
+}
+
+func TestIncrementalBackupParent(t *testing.T) {
+	db := testDB(t)
+
+	err := db.Exec(`CREATE TABLE users (id serial PRIMARY KEY, name text);
+		INSERT INTO users (name) VALUES ('ada'), ('alan')`).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	root := t.TempDir()
+	registry := &DatabaseRegistry{Tables: []string{"users"}}
+	backup := func(dir string, where map[string]string) *BackupManifest {
+		t.Helper()
+		// run ids have a one second resolution
+		time.Sleep(time.Second)
+		output := filepath.Join(root, dir)
+		if err := registry.Backup(db, BackupOptions{Output: output, Incremental: true, Where: where}); err != nil {
+			t.Fatal(err)
+		}
+		manifest, err := ReadManifest(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return manifest
+	}
+
+	full := backup("1", nil)
+	if full.Type != BackupFull {
+		t.Fatalf("first run is %s, want full", full.Type)
+	}
+
+	filtered := backup("2", map[string]string{"users": "id > 1"})
+	if filtered.Type != BackupFull || filtered.Parent != "" {
+		t.Errorf("run with another filter is %s based on %q, want a full backup", filtered.Type, filtered.Parent)
+	}
+
+	incremental := backup("3", map[string]string{"users": "id > 1"})
+	if incremental.Type != BackupIncremental || incremental.Parent != filtered.Run() {
+		t.Errorf("run with the same filter is %s based on %q, want incremental based on %s", incremental.Type, incremental.Parent, filtered.Run())
+	}
+}
@@ -0,0 +1,67 @@
+package structers
+
+import (
+	"fmt"
+	"path"
+	"slices"
+
+	"gorm.io/gorm"
+)
+
+// table sources of db:backup
+const (
+	TablesRegistry = "registry" // `Database.Tables`
+	TablesModels   = "models"   // `Database.Tables` and the tables of `Database.Models`
+	TablesSchema   = "schema"   // every table of the current schema
+)
+
+// BackupTables resolves the tables to back up from the source, filtered by the include and
+// exclude glob patterns (e.g. `audit_*`). Registry and model tables keep their registration order,
+// schema tables are sorted by name.
+func (r *DatabaseRegistry) BackupTables(db *gorm.DB, source string, include, exclude []string) ([]string, error) {
+	tables := slices.Clone(r.Tables)
+
+	switch source {
+	case "", TablesRegistry:
+	case TablesModels:
+		for _, model := range r.Models {
+			stmt := &gorm.Statement{DB: db}
+			if err := stmt.Parse(model); err != nil {
+				return nil, fmt.Errorf("failed to parse model %T: %v", model, err)
+			}
+			if !slices.Contains(tables, stmt.Schema.Table) {
+				tables = append(tables, stmt.Schema.Table)
+			}
+		}
+	case TablesSchema:
+		live, err := db.Migrator().GetTables()
+		if err != nil {
+			return nil, fmt.Errorf("failed to list tables: %v", err)
+		}
+		tables = slices.DeleteFunc(live, func(t string) bool { return t == (SchemaMigration{}).TableName() })
+		slices.Sort(tables)
+	default:
+		return nil, fmt.Errorf("unknown table source %q, expected registry, models or schema", source)
+	}
+
+	for _, pattern := range append(slices.Clone(include), exclude...) {
+		if _, err := path.Match(pattern, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %v", pattern, err)
+		}
+	}
+
+	return slices.DeleteFunc(tables, func(table string) bool {
+		return (len(include) > 0 && !matchAny(include, table)) || matchAny(exclude, table)
+	}), nil
+}
+
+// helper
+
+func matchAny(patterns []string, name string) bool {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, name); ok {
+			return true
+		}
+	}
+	return false
+}
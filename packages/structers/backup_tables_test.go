@@ -0,0 +1,114 @@
+package structers
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/utils/tests"
+)
+
+type invoice struct{ ID uint }
+
+type auditLog struct{ ID uint }
+
+func TestBackupTables(t *testing.T) {
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	registry := &DatabaseRegistry{
+		Tables: []string{"users", "orders", "audit_events"},
+		Models: []any{&invoice{}, &auditLog{}},
+	}
+
+	tests := []struct {
+		name    string
+		source  string
+		include []string
+		exclude []string
+		want    []string
+		wantErr string
+	}{
+		{name: "registry by default", want: []string{"users", "orders", "audit_events"}},
+		{name: "registry", source: TablesRegistry, want: []string{"users", "orders", "audit_events"}},
+		{name: "models after the registry", source: TablesModels, want: []string{"users", "orders", "audit_events", "invoices", "audit_logs"}},
+		{name: "include", include: []string{"audit_*"}, want: []string{"audit_events"}},
+		{name: "include several", source: TablesModels, include: []string{"audit_*", "users"}, want: []string{"users", "audit_events", "audit_logs"}},
+		{name: "exclude", source: TablesModels, exclude: []string{"audit_*"}, want: []string{"users", "orders", "invoices"}},
+		{name: "exclude wins over include", include: []string{"*s"}, exclude: []string{"orders"}, want: []string{"users", "audit_events"}},
+		{name: "single character", include: []string{"?sers"}, want: []string{"users"}},
+		{name: "nothing matches", include: []string{"products"}, want: []string{}},
+		{name: "invalid pattern", include: []string{"[users"}, wantErr: `invalid pattern "[users"`},
+		{name: "invalid exclude pattern", exclude: []string{"users["}, wantErr: `invalid pattern "users["`},
+		{name: "unknown source", source: "views", wantErr: `unknown table source "views"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := registry.BackupTables(db, tt.source, tt.include, tt.exclude)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tables = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if !reflect.DeepEqual(registry.Tables, []string{"users", "orders", "audit_events"}) {
+		t.Errorf("registry tables changed to %v", registry.Tables)
+	}
+}
+
+func TestBackupTablesSchema(t *testing.T) {
+	db := testDB(t)
+
+	err := db.Exec(`CREATE TABLE users (id int); CREATE TABLE audit_logs (id int);
+		CREATE TABLE invoices (id int); CREATE TABLE schema_migrations (version text)`).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	registry := &DatabaseRegistry{Tables: []string{"users"}}
+	got, err := registry.BackupTables(db, TablesSchema, nil, []string{"audit_*"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"invoices", "users"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("tables = %v, want %v", got, want)
+	}
+}
+
+func TestMatchAny(t *testing.T) {
+	tests := []struct {
+		patterns []string
+		name     string
+		want     bool
+	}{
+		{patterns: []string{"users"}, name: "users", want: true},
+		{patterns: []string{"audit_*"}, name: "audit_logs", want: true},
+		{patterns: []string{"audit_*"}, name: "audit", want: false},
+		{patterns: []string{"*_logs", "users"}, name: "users", want: true},
+		{patterns: []string{"public.*"}, name: "public.users", want: true},
+		{patterns: []string{"*"}, name: "public.users", want: true},
+		{patterns: []string{"user[sz]"}, name: "userz", want: true},
+		{patterns: []string{"[users"}, name: "[users", want: false},
+		{patterns: nil, name: "users", want: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(strings.Join(tt.patterns, ",")+" "+tt.name, func(t *testing.T) {
+			if got := matchAny(tt.patterns, tt.name); got != tt.want {
+				t.Errorf("matchAny(%v, %q) = %v, want %v", tt.patterns, tt.name, got, tt.want)
+			}
+		})
+	}
+}
@@ -1,14 +1,10 @@
 package structers
 
 import (
-	"encoding/json"
 	"fmt"
 	"log/slog"
-	"os"
-	"path/filepath"
 	"strings"
 	"time"
-	"webservices/packages/common"
 
 	"gorm.io/gorm"
 )
@@ -18,7 +14,23 @@ type DatabaseRegistry struct {
 	Models     []any
 	Extensions []string
 	Tables     []string
-	Factories  []func(*gorm.DB) error
+	Migrations string
+	Seeders    []Seeder
+
+	// BackupFormats registers custom db:backup formats by name, next to json, ndjson, csv and sql
+	BackupFormats map[string]BackupFormat
+
+	// BackupWhere filters the rows db:backup exports per table with a SQL condition
+	BackupWhere map[string]string
+
+	// Masks anonymize columns per table in `db:backup --profile=anonymized`
+	Masks map[string]map[string]Mask
+
+	// DropEnumValues removes enum values which are no longer registered on migrate, see `migrate --drop-enum-values`
+	DropEnumValues bool
+
+	// LockTimeout is how long a migration waits for the advisory lock held by another run
+	LockTimeout time.Duration
 }
 
 func (r *DatabaseRegistry) GetEnums() []Enum {
@@ -37,8 +49,12 @@ func (r *DatabaseRegistry) GetExtensions() []string {
 	return r.Extensions
 }
 
-func (r *DatabaseRegistry) GetFactories() []func(*gorm.DB) error {
-	return r.Factories
+func (r *DatabaseRegistry) GetMigrations() string {
+	return r.Migrations
+}
+
+func (r *DatabaseRegistry) GetSeeders() []Seeder {
+	return r.Seeders
 }
 
 func (r *DatabaseRegistry) Migrate(db *gorm.DB, fresh bool) error {
@@ -51,86 +67,76 @@ func (r *DatabaseRegistry) Migrate(db *gorm.DB, fresh bool) error {
 		}
 	}()
 
-	if fresh {
-		if err := dropAll(tx, r); err != nil {
-			tx.Rollback()
-			return err
-		}
-	}
-
-	if err := createExtensions(tx, r.Extensions); err != nil {
+	if err := r.migrate(tx, fresh, nil); err != nil {
 		tx.Rollback()
 		return err
 	}
 
-	if err := createEnums(tx, r.Enums); err != nil {
-		tx.Rollback()
+	if err := tx.Commit().Error; err != nil {
 		return err
 	}
 
-	if !fresh {
-		for _, enum := range r.Enums {
-			if err := tx.Exec(enum.UpdateQuery()).Error; err != nil {
-				slog.Error("Add enum value", slog.Any("error", err))
-			}
-		}
+	slog.Info("✅ Database migration successfully")
+	return nil
+}
+
+// Pretend computes the migration in a read only transaction that is always rolled back
+// and returns every statement that would change the database, none of them is executed.
+// Statements are computed against the current schema, AutoMigrate doesn't see the changes of pending migrations.
+func (r *DatabaseRegistry) Pretend(db *gorm.DB, fresh bool) ([]string, error) {
+	tx, recorder := beginDryRun(db)
+	if tx.Error != nil {
+		return nil, tx.Error
 	}
+	defer tx.Rollback()
 
-	if err := tx.AutoMigrate(r.Models...); err != nil {
-		tx.Rollback()
-		return err
+	// the drops are only recorded, what follows is computed for an empty database
+	dropped := func(tx *gorm.DB) error {
+		return tx.Statement.ConnPool.(*dryRunPool).emptySchema(tx.Statement.Context)
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		return err
+	if err := r.migrate(tx, fresh, dropped); err != nil {
+		return nil, err
 	}
 
-	slog.Info("✅ Database migration successfully")
-	return nil
+	return recorder.Statements(), nil
 }
 
-func (r *DatabaseRegistry) Backup(db *gorm.DB, output string) error {
-	slog.Info("Starting database backup...")
-
-	if err := os.MkdirAll(output, 0755); err != nil {
-		return fmt.Errorf("failed to create output directory: %v", err)
+// migrate runs every step of a migration, dropped is called after the `fresh` drops when set.
+func (r *DatabaseRegistry) migrate(tx *gorm.DB, fresh bool, dropped func(tx *gorm.DB) error) error {
+	if err := acquireMigrationLock(tx, r.LockTimeout); err != nil {
+		return err
 	}
 
-	timestamp := time.Now().Format("200601021504") // YYYYMMDDHHMM format
-
-	for _, table := range r.Tables {
-		var results []map[string]any
-		if err := db.Table(table).Find(&results).Error; err != nil {
-			return fmt.Errorf("failed to query table %s: %v", table, err)
+	if fresh {
+		if err := dropAll(tx, r); err != nil {
+			return err
 		}
-
-		for i, row := range results {
-			camelCaseRow := make(map[string]any)
-			for key, value := range row {
-				camelKey := common.ToCamelCase(key)
-				camelCaseRow[camelKey] = value
+		if dropped != nil {
+			if err := dropped(tx); err != nil {
+				return err
 			}
-			results[i] = camelCaseRow
 		}
+	}
 
-		filename := fmt.Sprintf("backup_%s_%s.json", table, timestamp)
-		filePath := filepath.Join(output, filename)
+	if err := createExtensions(tx, r.Extensions); err != nil {
+		return err
+	}
 
-		file, err := os.Create(filePath)
-		if err != nil {
-			return fmt.Errorf("failed to create file %s: %v", filePath, err)
+	for _, e := range r.Enums {
+		if err := syncEnum(tx, e, r.DropEnumValues); err != nil {
+			return err
 		}
-		defer file.Close()
+	}
 
-		encoder := json.NewEncoder(file)
-		encoder.SetIndent("", "  ")
-		if err := encoder.Encode(results); err != nil {
-			return fmt.Errorf("failed to encode JSON for table %s: %v", table, err)
+	// versioned migrations run before AutoMigrate so renames and backfills see the previous schema
+	if r.Migrations != "" {
+		if err := runMigrations(tx, r.Migrations); err != nil {
+			return err
 		}
 	}
 
-	slog.Info("✅ Database backup completed successfully")
-	return nil
+	return tx.AutoMigrate(r.Models...)
 }
 
 // helper
@@ -142,12 +148,16 @@ func dropAll(tx *gorm.DB, registry *DatabaseRegistry) error {
 	}
 
 	if len(tables) > 0 {
+		for i, t := range tables {
+			tables[i] = quoteIdent(t)
+		}
+
 		if err := tx.Exec(fmt.Sprintf("TRUNCATE %s CASCADE", strings.Join(tables, ", "))).Error; err != nil {
 			return err
 		}
 
 		for i, t := range tables {
-			tables[i] = fmt.Sprintf(`DROP TABLE IF EXISTS "%s" CASCADE`, t)
+			tables[i] = fmt.Sprintf("DROP TABLE IF EXISTS %s CASCADE", t)
 		}
 		if err := tx.Exec(strings.Join(tables, "; ")).Error; err != nil {
 			return err
@@ -167,7 +177,7 @@ func dropAll(tx *gorm.DB, registry *DatabaseRegistry) error {
 
 func createExtensions(tx *gorm.DB, exts []string) error {
 	for _, q := range exts {
-		query := fmt.Sprintf(`CREATE EXTENSION IF NOT EXISTS "%s"`, q)
+		query := fmt.Sprintf("CREATE EXTENSION IF NOT EXISTS %s", quoteIdent(q))
 		if err := tx.Exec(query).Error; err != nil {
 			return err
 		}
@@ -175,18 +185,9 @@ func createExtensions(tx *gorm.DB, exts []string) error {
 	return nil
 }
 
-func createEnums(tx *gorm.DB, enums []Enum) error {
-	for _, e := range enums {
-		if err := tx.Exec(e.CreateQuery()).Error; err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
 func dropEnums(tx *gorm.DB, names []string) error {
 	for _, name := range names {
-		if err := tx.Exec("DROP TYPE IF EXISTS " + name).Error; err != nil {
+		if err := tx.Exec("DROP TYPE IF EXISTS " + quoteIdent(name)).Error; err != nil {
 			return err
 		}
 	}
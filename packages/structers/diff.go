@@ -0,0 +1,480 @@
+package structers
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/schema"
+)
+
+// SchemaDiff is the difference between the registered models and the live database.
+type SchemaDiff struct {
+	MissingTables []string
+	ExtraTables   []string
+	Tables        []TableDiff
+
+	models map[string]any
+}
+
+// TableDiff is the difference of a single table that exists on both sides.
+type TableDiff struct {
+	Table              string
+	MissingColumns     []string
+	ExtraColumns       []string
+	TypeMismatches     []ColumnMismatch
+	NullMismatches     []ColumnMismatch
+	MissingIndexes     []string
+	ExtraIndexes       []string
+	MissingConstraints []string
+	ExtraConstraints   []string
+}
+
+// ColumnMismatch holds the expected (model) and actual (database) definition of a column.
+type ColumnMismatch struct {
+	Column   string
+	Expected string
+	Actual   string
+}
+
+func (d *SchemaDiff) Empty() bool {
+	return len(d.MissingTables) == 0 && len(d.ExtraTables) == 0 && len(d.Tables) == 0
+}
+
+func (d *TableDiff) Empty() bool {
+	return len(d.MissingColumns) == 0 && len(d.ExtraColumns) == 0 &&
+		len(d.TypeMismatches) == 0 && len(d.NullMismatches) == 0 &&
+		len(d.MissingIndexes) == 0 && len(d.ExtraIndexes) == 0 &&
+		len(d.MissingConstraints) == 0 && len(d.ExtraConstraints) == 0
+}
+
+// Diff inspects every registered model through the migrator and information_schema.
+func (r *DatabaseRegistry) Diff(db *gorm.DB) (*SchemaDiff, error) {
+	diff := &SchemaDiff{models: make(map[string]any)}
+	m := db.Migrator()
+
+	tables, err := m.GetTables()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, model := range r.Models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return nil, fmt.Errorf("failed to parse model %T: %v", model, err)
+		}
+
+		table := stmt.Schema.Table
+		diff.models[table] = model
+
+		if !slices.Contains(tables, table) {
+			diff.MissingTables = append(diff.MissingTables, table)
+			continue
+		}
+
+		td, err := diffTable(db, model, stmt.Schema)
+		if err != nil {
+			return nil, err
+		}
+		if !td.Empty() {
+			diff.Tables = append(diff.Tables, *td)
+		}
+	}
+
+	for _, table := range tables {
+		if _, ok := diff.models[table]; !ok && table != (SchemaMigration{}).TableName() {
+			diff.ExtraTables = append(diff.ExtraTables, table)
+		}
+	}
+
+	return diff, nil
+}
+
+// Reconcile returns the up and down statements of a migration that brings the database in line with the models.
+// The statements are built through the migrator in a dry run, nothing is executed.
+// Extra columns, indexes and constraints are only dropped with drop, their down steps recreate them
+// from the current definition, without the data. Extra tables are never dropped.
+func (r *DatabaseRegistry) Reconcile(db *gorm.DB, diff *SchemaDiff, drop bool) (up []string, down []string, err error) {
+	tx, recorder := beginDryRun(db)
+	if tx.Error != nil {
+		return nil, nil, tx.Error
+	}
+	defer tx.Rollback()
+
+	m := tx.Migrator()
+	step := func(reverse string, fn func() error) error {
+		before := len(recorder.Statements())
+		if err := fn(); err != nil {
+			return err
+		}
+		down = append([]string{reverse}, down...)
+		up = append(up, recorder.Statements()[before:]...)
+		return nil
+	}
+
+	for _, table := range diff.MissingTables {
+		if err = step(fmt.Sprintf("DROP TABLE IF EXISTS %s;", quoteIdent(table)), func() error {
+			return m.CreateTable(diff.models[table])
+		}); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, td := range diff.Tables {
+		model := diff.models[td.Table]
+		table := quoteIdent(td.Table)
+
+		for _, name := range td.MissingColumns {
+			if err = step(fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s;", table, quoteIdent(name)), func() error {
+				return m.AddColumn(model, name)
+			}); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		for _, c := range td.TypeMismatches {
+			reverse := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s;",
+				table, quoteIdent(c.Column), c.Actual, quoteIdent(c.Column), c.Actual)
+			if err = step(reverse, func() error {
+				return m.AlterColumn(model, c.Column)
+			}); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		for _, c := range td.NullMismatches {
+			set, unset := "SET NOT NULL", "DROP NOT NULL"
+			if c.Expected == "NULL" {
+				set, unset = unset, set
+			}
+			if err = step(fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s;", table, quoteIdent(c.Column), unset), func() error {
+				return tx.Exec(fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s", table, quoteIdent(c.Column), set)).Error
+			}); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		for _, name := range td.MissingIndexes {
+			if err = step(fmt.Sprintf("DROP INDEX IF EXISTS %s;", quoteIdent(name)), func() error {
+				return m.CreateIndex(model, name)
+			}); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		for _, name := range td.MissingConstraints {
+			if err = step(fmt.Sprintf("ALTER TABLE %s DROP CONSTRAINT IF EXISTS %s;", table, quoteIdent(name)), func() error {
+				return m.CreateConstraint(model, name)
+			}); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		if !drop {
+			continue
+		}
+
+		// down recreates what is dropped from its current definition, the data is lost
+		for _, name := range td.ExtraConstraints {
+			definition, err := constraintDefinition(tx, td.Table, name)
+			if err != nil {
+				return nil, nil, err
+			}
+			reverse := fmt.Sprintf("ALTER TABLE %s ADD CONSTRAINT %s %s;", table, quoteIdent(name), definition)
+			if err = step(reverse, func() error {
+				return m.DropConstraint(model, name)
+			}); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		for _, name := range td.ExtraIndexes {
+			definition, err := indexDefinition(tx, td.Table, name)
+			if err != nil {
+				return nil, nil, err
+			}
+			if err = step(definition+";", func() error {
+				return m.DropIndex(model, name)
+			}); err != nil {
+				return nil, nil, err
+			}
+		}
+
+		for _, name := range td.ExtraColumns {
+			definition, err := columnDefinition(tx, td.Table, name)
+			if err != nil {
+				return nil, nil, err
+			}
+			reverse := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s;", table, definition)
+			if err = step(reverse, func() error {
+				return m.DropColumn(model, name)
+			}); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	return up, down, nil
+}
+
+// helper
+
+// columnDefinition is the current definition of a column, e.g. `"age" integer NOT NULL DEFAULT 0`.
+func columnDefinition(db *gorm.DB, table, column string) (string, error) {
+	var def struct {
+		Type         string
+		NotNull      bool
+		DefaultValue *string
+	}
+	err := db.Raw(`SELECT format_type(a.atttypid, a.atttypmod) AS type, a.attnotnull AS not_null,
+			pg_get_expr(d.adbin, d.adrelid) AS default_value
+		FROM pg_attribute a
+		JOIN pg_class c ON c.oid = a.attrelid
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE c.relname = ? AND c.relnamespace = current_schema()::regnamespace
+		AND a.attname = ? AND a.attnum > 0 AND NOT a.attisdropped`, table, column).Scan(&def).Error
+	if err != nil {
+		return "", err
+	}
+	if def.Type == "" {
+		return "", fmt.Errorf("column %s.%s not found", table, column)
+	}
+
+	// a NOT NULL column without default can't be added back to a table with rows
+	definition := quoteIdent(column) + " " + def.Type
+	if def.DefaultValue != nil {
+		definition += " DEFAULT " + *def.DefaultValue
+		if def.NotNull {
+			definition += " NOT NULL"
+		}
+	}
+	return definition, nil
+}
+
+// columnTypeNames are the types of the columns of a table as SQL writes them, e.g. `character varying(255)`.
+func columnTypeNames(db *gorm.DB, table string) (map[string]string, error) {
+	var columns []struct {
+		Name string
+		Type string
+	}
+	err := db.Raw(`SELECT attname AS name, format_type(atttypid, atttypmod) AS type FROM pg_attribute
+		WHERE attrelid = to_regclass(?) AND attnum > 0 AND NOT attisdropped`, quoteIdent(table)).Scan(&columns).Error
+	if err != nil {
+		return nil, err
+	}
+
+	types := make(map[string]string, len(columns))
+	for _, c := range columns {
+		types[c.Name] = c.Type
+	}
+	return types, nil
+}
+
+// indexDefinition is the `CREATE INDEX` statement of an index.
+func indexDefinition(db *gorm.DB, table, index string) (string, error) {
+	var definition string
+	err := db.Raw(`SELECT indexdef FROM pg_indexes
+		WHERE schemaname = current_schema() AND tablename = ? AND indexname = ?`, table, index).Scan(&definition).Error
+	if err != nil {
+		return "", err
+	}
+	if definition == "" {
+		return "", fmt.Errorf("index %s on %s not found", index, table)
+	}
+	return definition, nil
+}
+
+// constraintDefinition is the definition of a constraint, e.g. `FOREIGN KEY (user_id) REFERENCES users(id)`.
+func constraintDefinition(db *gorm.DB, table, constraint string) (string, error) {
+	var definition string
+	err := db.Raw(`SELECT pg_get_constraintdef(con.oid) FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		WHERE c.relname = ? AND c.relnamespace = current_schema()::regnamespace AND con.conname = ?`,
+		table, constraint).Scan(&definition).Error
+	if err != nil {
+		return "", err
+	}
+	if definition == "" {
+		return "", fmt.Errorf("constraint %s on %s not found", constraint, table)
+	}
+	return definition, nil
+}
+
+func diffTable(db *gorm.DB, model any, sch *schema.Schema) (*TableDiff, error) {
+	m := db.Migrator()
+	td := &TableDiff{Table: sch.Table}
+
+	columnTypes, err := m.ColumnTypes(model)
+	if err != nil {
+		return nil, err
+	}
+
+	actual := make(map[string]gorm.ColumnType, len(columnTypes))
+	for _, ct := range columnTypes {
+		actual[ct.Name()] = ct
+	}
+
+	// the down steps restore these types, format_type keeps the precision of e.g. numeric(10,2)
+	formatted, err := columnTypeNames(db, sch.Table)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, field := range sch.Fields {
+		if field.DBName == "" || field.IgnoreMigration {
+			continue
+		}
+
+		ct, ok := actual[field.DBName]
+		if !ok {
+			td.MissingColumns = append(td.MissingColumns, field.DBName)
+			continue
+		}
+		delete(actual, field.DBName)
+
+		expected := db.Dialector.DataTypeOf(field)
+		if !sameType(expected, ct) {
+			td.TypeMismatches = append(td.TypeMismatches, ColumnMismatch{Column: field.DBName, Expected: expected, Actual: formatted[field.DBName]})
+		}
+
+		notNull := field.NotNull || field.PrimaryKey
+		if nullable, ok := ct.Nullable(); ok && nullable == notNull {
+			mismatch := ColumnMismatch{Column: field.DBName, Expected: "NOT NULL", Actual: "NULL"}
+			if !notNull {
+				mismatch.Expected, mismatch.Actual = "NULL", "NOT NULL"
+			}
+			td.NullMismatches = append(td.NullMismatches, mismatch)
+		}
+	}
+
+	for name := range actual {
+		td.ExtraColumns = append(td.ExtraColumns, name)
+	}
+	slices.Sort(td.ExtraColumns)
+
+	// indexes
+	indexes, err := m.GetIndexes(model)
+	if err != nil {
+		return nil, err
+	}
+
+	expectedIndexes := make(map[string]bool)
+	for _, idx := range sch.ParseIndexes() {
+		expectedIndexes[idx.Name] = true
+	}
+
+	for _, idx := range indexes {
+		name := idx.Name()
+		if expectedIndexes[name] {
+			delete(expectedIndexes, name)
+			continue
+		}
+		if primary, _ := idx.PrimaryKey(); primary || isUniqueFieldIndex(sch, idx.Columns()) {
+			continue
+		}
+		td.ExtraIndexes = append(td.ExtraIndexes, name)
+	}
+
+	for name := range expectedIndexes {
+		td.MissingIndexes = append(td.MissingIndexes, name)
+	}
+	slices.Sort(td.MissingIndexes)
+	slices.Sort(td.ExtraIndexes)
+
+	// foreign key and check constraints
+	expectedConstraints := make(map[string]bool)
+	for _, rel := range sch.Relationships.Relations {
+		if rel.Field.IgnoreMigration {
+			continue
+		}
+		if c := rel.ParseConstraint(); c != nil && c.Schema == sch {
+			expectedConstraints[c.Name] = true
+		}
+	}
+	for _, c := range sch.ParseCheckConstraints() {
+		expectedConstraints[c.Name] = true
+	}
+
+	var constraints []string
+	err = db.Raw(`SELECT constraint_name FROM information_schema.table_constraints
+		WHERE table_schema = current_schema() AND table_name = ?
+		AND constraint_type IN ('FOREIGN KEY', 'CHECK') AND constraint_name NOT LIKE '%_not_null'`,
+		sch.Table).Scan(&constraints).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range constraints {
+		if expectedConstraints[name] {
+			delete(expectedConstraints, name)
+			continue
+		}
+		td.ExtraConstraints = append(td.ExtraConstraints, name)
+	}
+
+	for name := range expectedConstraints {
+		td.MissingConstraints = append(td.MissingConstraints, name)
+	}
+	slices.Sort(td.MissingConstraints)
+	slices.Sort(td.ExtraConstraints)
+
+	return td, nil
+}
+
+// isUniqueFieldIndex reports whether the index backs a `unique` field, which is not part of ParseIndexes.
+func isUniqueFieldIndex(sch *schema.Schema, columns []string) bool {
+	if len(columns) != 1 {
+		return false
+	}
+	field := sch.LookUpField(columns[0])
+	return field != nil && field.Unique
+}
+
+var typeSize = regexp.MustCompile(`^([a-z0-9_ ]+?)\s*(?:\(([0-9, ]+)\))?(\[\])?$`)
+
+var typeAliases = map[string]string{
+	"bigint": "int8", "bigserial": "int8", "serial8": "int8",
+	"integer": "int4", "int": "int4", "serial": "int4", "serial4": "int4",
+	"smallint": "int2", "smallserial": "int2", "serial2": "int2",
+	"boolean": "bool", "decimal": "numeric",
+	"timestamp with time zone": "timestamptz", "timestamp without time zone": "timestamp",
+	"character varying": "varchar", "character": "bpchar", "char": "bpchar",
+	"real": "float4", "double precision": "float8",
+}
+
+// sameType compares a model data type (e.g. `bigint`, `varchar(255)`) with the database column.
+func sameType(expected string, ct gorm.ColumnType) bool {
+	match := typeSize.FindStringSubmatch(strings.ToLower(strings.TrimSpace(expected)))
+	if match == nil {
+		// custom definitions we can't parse are trusted
+		return true
+	}
+
+	base := match[1]
+	if alias, ok := typeAliases[base]; ok {
+		base = alias
+	}
+	if match[3] != "" {
+		base = "_" + base
+	}
+
+	actual := strings.ToLower(ct.DatabaseTypeName())
+	if alias, ok := typeAliases[actual]; ok {
+		actual = alias
+	}
+	if base != actual {
+		return false
+	}
+
+	if match[2] != "" && base == "varchar" {
+		size, _ := strconv.ParseInt(match[2], 10, 64)
+		if length, ok := ct.Length(); ok && length != size {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,124 @@
+package structers
+
+import (
+	"database/sql"
+	"slices"
+	"testing"
+
+	"gorm.io/gorm/migrator"
+)
+
+func TestSameType(t *testing.T) {
+	column := func(name string, length int64) migrator.ColumnType {
+		return migrator.ColumnType{
+			DataTypeValue: sql.NullString{String: name, Valid: true},
+			LengthValue:   sql.NullInt64{Int64: length, Valid: length > 0},
+		}
+	}
+
+	tests := []struct {
+		expected string
+		actual   migrator.ColumnType
+		want     bool
+	}{
+		{expected: "bigint", actual: column("int8", 0), want: true},
+		{expected: "bigserial", actual: column("int8", 0), want: true},
+		{expected: "integer", actual: column("int4", 0), want: true},
+		{expected: "smallint", actual: column("int4", 0), want: false},
+		{expected: "boolean", actual: column("bool", 0), want: true},
+		{expected: "decimal", actual: column("numeric", 0), want: true},
+		{expected: "numeric(10,2)", actual: column("numeric", 0), want: true},
+		{expected: "timestamptz", actual: column("timestamptz", 0), want: true},
+		{expected: "timestamp with time zone", actual: column("timestamptz", 0), want: true},
+		{expected: "timestamp", actual: column("timestamptz", 0), want: false},
+		{expected: "varchar(255)", actual: column("varchar", 255), want: true},
+		{expected: "VARCHAR(255)", actual: column("varchar", 255), want: true},
+		{expected: "varchar(255)", actual: column("varchar", 100), want: false},
+		{expected: "character varying(20)", actual: column("varchar", 20), want: true},
+		{expected: "text", actual: column("varchar", 255), want: false},
+		{expected: "char(2)", actual: column("bpchar", 2), want: true},
+		{expected: "text[]", actual: column("_text", 0), want: true},
+		{expected: "text[]", actual: column("text", 0), want: false},
+		{expected: "double precision", actual: column("float8", 0), want: true},
+		{expected: "uuid", actual: column("uuid", 0), want: true},
+		{expected: "mood", actual: column("mood", 0), want: true},
+		{expected: "mood", actual: column("text", 0), want: false},
+		// definitions that don't parse are trusted
+		{expected: "numeric(10,2) DEFAULT 0", actual: column("text", 0), want: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expected+" "+tt.actual.DatabaseTypeName(), func(t *testing.T) {
+			if got := sameType(tt.expected, tt.actual); got != tt.want {
+				t.Errorf("sameType(%q, %s) = %v, want %v", tt.expected, tt.actual.DatabaseTypeName(), got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTypeSize(t *testing.T) {
+	tests := []struct {
+		typ   string
+		base  string
+		size  string
+		array string
+		ok    bool
+	}{
+		{typ: "bigint", base: "bigint", ok: true},
+		{typ: "varchar(255)", base: "varchar", size: "255", ok: true},
+		{typ: "numeric (10, 2)", base: "numeric", size: "10, 2", ok: true},
+		{typ: "timestamp with time zone", base: "timestamp with time zone", ok: true},
+		{typ: "text[]", base: "text", array: "[]", ok: true},
+		{typ: "varchar(64)[]", base: "varchar", size: "64", array: "[]", ok: true},
+		{typ: "bigint not null", base: "bigint not null", ok: true},
+		{typ: "numeric(10,2) default 0", ok: false},
+		{typ: `"quoted"`, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.typ, func(t *testing.T) {
+			match := typeSize.FindStringSubmatch(tt.typ)
+			if (match != nil) != tt.ok {
+				t.Fatalf("typeSize matched %q: %v, want %v", tt.typ, match != nil, tt.ok)
+			}
+			if match != nil && (match[1] != tt.base || match[2] != tt.size || match[3] != tt.array) {
+				t.Errorf("typeSize(%q) = %q, %q, %q, want %q, %q, %q", tt.typ, match[1], match[2], match[3], tt.base, tt.size, tt.array)
+			}
+		})
+	}
+}
+
+type priced struct {
+	ID     uint
+	Amount int64
+}
+
+func (priced) TableName() string { return "prices" }
+
+func TestDiffRestoresTheColumnPrecision(t *testing.T) {
+	db := testDB(t)
+	if err := db.Exec("CREATE TABLE prices (id bigserial PRIMARY KEY, amount numeric(10,2))").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	registry := &DatabaseRegistry{Models: []any{&priced{}}}
+	diff, err := registry.Diff(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(diff.Tables) != 1 || len(diff.Tables[0].TypeMismatches) != 1 {
+		t.Fatalf("diff = %+v, want the amount type mismatch", diff.Tables)
+	}
+	if got := diff.Tables[0].TypeMismatches[0].Actual; got != "numeric(10,2)" {
+		t.Errorf("actual type = %q, want numeric(10,2)", got)
+	}
+
+	_, down, err := registry.Reconcile(db, diff, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `ALTER TABLE "prices" ALTER COLUMN "amount" TYPE numeric(10,2) USING "amount"::numeric(10,2);`
+	if !slices.Contains(down, want) {
+		t.Errorf("down = %v, want %s", down, want)
+	}
+}
@@ -2,26 +2,279 @@ package structers
 
 import (
 	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
 	"strings"
+
+	"gorm.io/gorm"
 )
 
 type Enum struct {
 	Name   string
 	Values []string
+	// Renames maps an old value to its new name, example: {"female": "woman"}
+	Renames map[string]string
+}
+
+// EnumDrift describes how an enum in the database differs from the registry.
+type EnumDrift struct {
+	Name       string
+	Registered bool
+	Exists     bool
+	Labels     []string     // values currently in the database
+	Missing    []string     // registered values not in the database
+	Extra      []string     // database values not registered, removed by `migrate --drop-enum-values`
+	Renames    []EnumRename // pending renames, in the order they apply
+}
+
+// EnumRename is a pending rename of an enum value.
+type EnumRename struct {
+	From string
+	To   string
 }
 
+func (d *EnumDrift) InSync() bool {
+	return d.Registered && d.Exists && len(d.Missing) == 0 && len(d.Extra) == 0 && len(d.Renames) == 0
+}
+
+// CreateQuery creates the type unless it exists, postgres has no `CREATE TYPE IF NOT EXISTS`.
 func (e *Enum) CreateQuery() string {
-	return fmt.Sprintf("CREATE TYPE IF NOT EXISTS %s AS ENUM ('%s')", e.Name, strings.Join(e.Values, "', '"))
+	return fmt.Sprintf("DO $enum$ BEGIN\n\t%s;\nEXCEPTION\n\tWHEN duplicate_object THEN NULL;\nEND $enum$",
+		createTypeQuery(e.Name, e.Values))
 }
 
 func (e *Enum) UpdateQuery() string {
 	var queries []string
 	for _, v := range e.Values {
-		queries = append(queries, fmt.Sprintf("ALTER TYPE %s ADD VALUE IF NOT EXISTS '%s';", e.Name, v))
+		queries = append(queries, fmt.Sprintf("ALTER TYPE %s ADD VALUE IF NOT EXISTS %s;", quoteIdent(e.Name), quoteLiteral(v)))
 	}
 	return strings.Join(queries, "\n")
 }
 
+func (e *Enum) RenameQuery(from, to string) string {
+	return fmt.Sprintf("ALTER TYPE %s RENAME VALUE %s TO %s", quoteIdent(e.Name), quoteLiteral(from), quoteLiteral(to))
+}
+
 func (e *Enum) DropQuery() string {
-	return fmt.Sprintf("DROP TYPE IF EXISTS %s", e.Name)
+	return fmt.Sprintf("DROP TYPE IF EXISTS %s", quoteIdent(e.Name))
+}
+
+// Diff compares the registered values with the labels currently in the database.
+func (e *Enum) Diff(labels []string) EnumDrift {
+	drift := EnumDrift{Name: e.Name, Registered: true, Exists: labels != nil, Labels: labels}
+	if !drift.Exists {
+		drift.Missing = e.Values
+		return drift
+	}
+
+	// a rename applies once its target is free, chained renames (a -> b, b -> c) run b -> c first
+	current := slices.Clone(labels)
+	pending := slices.Sorted(maps.Keys(e.Renames))
+	for {
+		i := slices.IndexFunc(pending, func(from string) bool {
+			return slices.Contains(current, from) && !slices.Contains(current, e.Renames[from])
+		})
+		if i < 0 {
+			break
+		}
+
+		from, to := pending[i], e.Renames[pending[i]]
+		current[slices.Index(current, from)] = to
+		drift.Renames = append(drift.Renames, EnumRename{From: from, To: to})
+		pending = slices.Delete(pending, i, i+1)
+	}
+
+	for _, v := range e.Values {
+		if !slices.Contains(current, v) {
+			drift.Missing = append(drift.Missing, v)
+		}
+	}
+
+	for _, v := range current {
+		if !slices.Contains(e.Values, v) {
+			drift.Extra = append(drift.Extra, v)
+		}
+	}
+
+	return drift
+}
+
+// EnumDrift reports every registered enum and every unregistered enum type in the database.
+func (r *DatabaseRegistry) EnumDrift(db *gorm.DB) ([]EnumDrift, error) {
+	var result []EnumDrift
+	registered := make(map[string]bool, len(r.Enums))
+
+	for _, e := range r.Enums {
+		labels, err := enumLabels(db, e.Name)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, e.Diff(labels))
+		registered[e.Name] = true
+	}
+
+	var names []string
+	err := db.Raw(`SELECT t.typname FROM pg_type t
+		JOIN pg_namespace n ON n.oid = t.typnamespace
+		WHERE t.typtype = 'e' AND n.nspname = current_schema()
+		ORDER BY t.typname`).Scan(&names).Error
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range names {
+		if registered[name] {
+			continue
+		}
+		labels, err := enumLabels(db, name)
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, EnumDrift{Name: name, Exists: true, Labels: labels, Extra: labels})
+	}
+
+	return result, nil
+}
+
+// helper
+
+type enumColumn struct {
+	SchemaName   string
+	TableName    string
+	ColumnName   string
+	IsArray      bool
+	DefaultValue *string
+}
+
+func createTypeQuery(name string, values []string) string {
+	return fmt.Sprintf("CREATE TYPE %s AS ENUM (%s)", quoteIdent(name), quoteLiterals(values))
+}
+
+// enumLabels returns the values of an enum type in sort order, nil when the type does not exist.
+func enumLabels(db *gorm.DB, name string) ([]string, error) {
+	var exists bool
+	if err := db.Raw("SELECT to_regtype(?) IS NOT NULL", quoteIdent(name)).Scan(&exists).Error; err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, nil
+	}
+
+	labels := []string{}
+	err := db.Raw(`SELECT enumlabel FROM pg_enum
+		WHERE enumtypid = to_regtype(?) ORDER BY enumsortorder`, quoteIdent(name)).Scan(&labels).Error
+	return labels, err
+}
+
+// syncEnum creates the enum or brings an existing one in line with the registry:
+// renames values and adds missing ones, extra values are only removed through a type swap with drop.
+func syncEnum(tx *gorm.DB, e Enum, drop bool) error {
+	labels, err := enumLabels(tx, e.Name)
+	if err != nil {
+		return err
+	}
+
+	drift := e.Diff(labels)
+	if !drift.Exists {
+		return tx.Exec(e.CreateQuery()).Error
+	}
+
+	for _, r := range drift.Renames {
+		if err := tx.Exec(e.RenameQuery(r.From, r.To)).Error; err != nil {
+			return fmt.Errorf("failed to rename enum value %s.%s: %v", e.Name, r.From, err)
+		}
+		slog.Info("Renamed enum value", slog.String("enum", e.Name), slog.String("from", r.From), slog.String("to", r.To))
+	}
+
+	if len(drift.Extra) > 0 {
+		if drop {
+			return swapEnum(tx, e, drift.Extra)
+		}
+		slog.Warn("Enum has unregistered values, remove them with migrate --drop-enum-values",
+			slog.String("enum", e.Name), slog.Any("values", drift.Extra))
+	}
+
+	if len(drift.Missing) > 0 {
+		if err := tx.Exec(e.UpdateQuery()).Error; err != nil {
+			return fmt.Errorf("failed to add enum values to %s: %v", e.Name, err)
+		}
+	}
+
+	return nil
+}
+
+// swapEnum removes values from an enum, postgres has no `DROP VALUE` so the type is
+// recreated and every dependent column is rewritten to the new type.
+func swapEnum(tx *gorm.DB, e Enum, removed []string) error {
+	var columns []enumColumn
+	err := tx.Raw(`SELECT n.nspname AS schema_name, c.relname AS table_name, a.attname AS column_name,
+			a.atttypid = t.typarray AS is_array, pg_get_expr(d.adbin, d.adrelid) AS default_value
+		FROM pg_type t
+		JOIN pg_attribute a ON a.atttypid IN (t.oid, t.typarray) AND a.attnum > 0 AND NOT a.attisdropped
+		JOIN pg_class c ON c.oid = a.attrelid AND c.relkind IN ('r', 'p')
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		LEFT JOIN pg_attrdef d ON d.adrelid = a.attrelid AND d.adnum = a.attnum
+		WHERE t.oid = to_regtype(?)`, quoteIdent(e.Name)).Scan(&columns).Error
+	if err != nil {
+		return err
+	}
+
+	for _, col := range columns {
+		var count int64
+		query := fmt.Sprintf(`SELECT count(*) FROM %s.%s WHERE %s::text IN ?`,
+			quoteIdent(col.SchemaName), quoteIdent(col.TableName), quoteIdent(col.ColumnName))
+		if col.IsArray {
+			query = fmt.Sprintf(`SELECT count(*) FROM %s.%s WHERE EXISTS (SELECT 1 FROM unnest(%s) AS v WHERE v::text IN ?)`,
+				quoteIdent(col.SchemaName), quoteIdent(col.TableName), quoteIdent(col.ColumnName))
+		}
+		if err := tx.Raw(query, removed).Scan(&count).Error; err != nil {
+			return err
+		}
+		if count > 0 {
+			return fmt.Errorf("cannot remove values %v from enum %s: %d rows in %s.%s still use them",
+				removed, e.Name, count, col.TableName, col.ColumnName)
+		}
+	}
+
+	// RENAME TO takes an unqualified name, the renamed type stays in the same schema
+	old := e.Name + "_old"
+	_, oldName, qualified := strings.Cut(old, ".")
+	if !qualified {
+		oldName = old
+	}
+
+	queries := []string{
+		fmt.Sprintf("ALTER TYPE %s RENAME TO %s", quoteIdent(e.Name), quoteIdent(oldName)),
+		createTypeQuery(e.Name, e.Values),
+	}
+
+	for _, col := range columns {
+		table := quoteIdent(col.SchemaName) + "." + quoteIdent(col.TableName)
+		column := quoteIdent(col.ColumnName)
+		source, target := "text", quoteIdent(e.Name)
+		if col.IsArray {
+			source, target = "text[]", target+"[]"
+		}
+
+		if col.DefaultValue != nil {
+			queries = append(queries, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP DEFAULT", table, column))
+		}
+		queries = append(queries, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE %s USING %s::%s::%s",
+			table, column, target, column, source, target))
+		if col.DefaultValue != nil {
+			queries = append(queries, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s SET DEFAULT %s", table, column, *col.DefaultValue))
+		}
+	}
+
+	queries = append(queries, fmt.Sprintf("DROP TYPE %s", quoteIdent(old)))
+
+	for _, q := range queries {
+		if err := tx.Exec(q).Error; err != nil {
+			return fmt.Errorf("failed to remove values from enum %s: %v", e.Name, err)
+		}
+	}
+
+	slog.Info("Removed enum values", slog.String("enum", e.Name), slog.Any("values", removed))
+	return nil
 }
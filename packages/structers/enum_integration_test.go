@@ -0,0 +1,101 @@
+package structers
+
+import (
+	"slices"
+	"testing"
+
+	"gorm.io/gorm"
+)
+
+func TestEnumCreateQueryIsIdempotent(t *testing.T) {
+	db := testDB(t)
+
+	tests := []Enum{
+		{Name: "status", Values: []string{"active", "inactive"}},
+		{Name: "Mixed Case", Values: []string{"a b", "C"}},
+		{Name: `we"ird`, Values: []string{"it's", `back\slash`, "'; DROP TABLE users; --"}},
+	}
+
+	for _, e := range tests {
+		t.Run(e.Name, func(t *testing.T) {
+			for range 2 {
+				if err := db.Exec(e.CreateQuery()).Error; err != nil {
+					t.Fatalf("CreateQuery: %v", err)
+				}
+			}
+
+			labels, err := enumLabels(db, e.Name)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(labels, e.Values) {
+				t.Errorf("labels = %q, want %q", labels, e.Values)
+			}
+
+			if err := db.Exec(e.UpdateQuery()).Error; err != nil {
+				t.Errorf("UpdateQuery: %v", err)
+			}
+			if err := db.Exec(e.DropQuery()).Error; err != nil {
+				t.Errorf("DropQuery: %v", err)
+			}
+		})
+	}
+}
+
+func TestSyncEnum(t *testing.T) {
+	db := testDB(t)
+
+	sync := func(e Enum, drop bool) []string {
+		t.Helper()
+		err := db.Transaction(func(tx *gorm.DB) error { return syncEnum(tx, e, drop) })
+		if err != nil {
+			t.Fatalf("syncEnum: %v", err)
+		}
+		labels, err := enumLabels(db, e.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return labels
+	}
+
+	if labels := sync(Enum{Name: "level", Values: []string{"a", "b"}}, false); !slices.Equal(labels, []string{"a", "b"}) {
+		t.Fatalf("created labels = %v", labels)
+	}
+
+	// created twice, e.g. by two replicas migrating at startup
+	if labels := sync(Enum{Name: "level", Values: []string{"a", "b"}}, false); !slices.Equal(labels, []string{"a", "b"}) {
+		t.Fatalf("labels after second sync = %v", labels)
+	}
+
+	chained := Enum{Name: "level", Values: []string{"b", "c", "d"}, Renames: map[string]string{"a": "b", "b": "c"}}
+	if labels := sync(chained, false); !slices.Equal(labels, []string{"b", "c", "d"}) {
+		t.Fatalf("labels after renames = %v", labels)
+	}
+
+	if err := db.Exec("CREATE TABLE items (level level, levels level[])").Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Exec("INSERT INTO items VALUES ('c', '{b}')").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	// unregistered values are kept without drop
+	if labels := sync(Enum{Name: "level", Values: []string{"c", "d"}}, false); !slices.Equal(labels, []string{"b", "c", "d"}) {
+		t.Fatalf("labels without drop = %v", labels)
+	}
+
+	// b is still used by the array column
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return syncEnum(tx, Enum{Name: "level", Values: []string{"c", "d"}}, true)
+	})
+	if err == nil {
+		t.Fatal("removing a value used by an array column succeeded")
+	}
+
+	if err := db.Exec("UPDATE items SET levels = '{d}'").Error; err != nil {
+		t.Fatal(err)
+	}
+	if labels := sync(Enum{Name: "level", Values: []string{"c", "d"}}, true); !slices.Equal(labels, []string{"c", "d"}) {
+		t.Fatalf("labels after drop = %v", labels)
+	}
+}
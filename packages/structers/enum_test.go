@@ -0,0 +1,70 @@
+package structers
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEnumDiff(t *testing.T) {
+	tests := []struct {
+		name    string
+		enum    Enum
+		labels  []string
+		renames []EnumRename
+		missing []string
+		extra   []string
+	}{
+		{
+			name:    "missing type",
+			enum:    Enum{Name: "status", Values: []string{"a", "b"}},
+			labels:  nil,
+			missing: []string{"a", "b"},
+		},
+		{
+			name:    "added and extra values",
+			enum:    Enum{Name: "status", Values: []string{"a", "c"}},
+			labels:  []string{"a", "b"},
+			missing: []string{"c"},
+			extra:   []string{"b"},
+		},
+		{
+			name:    "rename",
+			enum:    Enum{Name: "gender", Values: []string{"man", "woman"}, Renames: map[string]string{"female": "woman"}},
+			labels:  []string{"man", "female"},
+			renames: []EnumRename{{From: "female", To: "woman"}},
+		},
+		{
+			name:    "chained renames apply b -> c first",
+			enum:    Enum{Name: "level", Values: []string{"b", "c"}, Renames: map[string]string{"a": "b", "b": "c"}},
+			labels:  []string{"a", "b"},
+			renames: []EnumRename{{From: "b", To: "c"}, {From: "a", To: "b"}},
+		},
+		{
+			name:    "rename of an absent value is skipped",
+			enum:    Enum{Name: "status", Values: []string{"a"}, Renames: map[string]string{"x": "y"}},
+			labels:  []string{"a"},
+			renames: nil,
+		},
+		{
+			name:    "cyclic renames are skipped",
+			enum:    Enum{Name: "status", Values: []string{"a", "b"}, Renames: map[string]string{"a": "b", "b": "a"}},
+			labels:  []string{"a", "b"},
+			renames: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			drift := tt.enum.Diff(tt.labels)
+			if !reflect.DeepEqual(drift.Renames, tt.renames) {
+				t.Errorf("renames = %v, want %v", drift.Renames, tt.renames)
+			}
+			if !reflect.DeepEqual(drift.Missing, tt.missing) {
+				t.Errorf("missing = %v, want %v", drift.Missing, tt.missing)
+			}
+			if !reflect.DeepEqual(drift.Extra, tt.extra) {
+				t.Errorf("extra = %v, want %v", drift.Extra, tt.extra)
+			}
+		})
+	}
+}
@@ -0,0 +1,469 @@
+package structers
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"gorm.io/gorm"
+)
+
+// fixture files are YAML or JSON documents keyed by table, whose rows are a list or a map keyed by label:
+//
+//	plans:
+//	  _unique: code        # columns to upsert on, the primary key by default
+//	  free: {code: free, price: 0}
+//	  pro:  {code: pro, price: 10}
+//	subscriptions:
+//	  - {plan_id: "@plans.pro.id", email: admin@example.com}
+//
+// `@table.label.column` is replaced by the column of a labeled row once saved, `@@` escapes a leading `@`.
+// A JSON array, like the files of `db:backup --format=json`, holds the rows of the table its file is named after.
+var fixtureExtensions = []string{".yaml", ".yml", ".json"}
+
+// FixtureResult is the number of rows a fixture load saved in a table.
+type FixtureResult struct {
+	Table string
+	Rows  int64
+}
+
+type fixtureTable struct {
+	name    string
+	unique  []string
+	rows    []fixtureRow
+	backups []string // files in the db:backup shape, streamed
+}
+
+type fixtureRow struct {
+	label  string
+	file   string
+	values map[string]any
+}
+
+// LoadFixtures upserts the fixture files of a directory, or a single file, in one transaction.
+// Tables are loaded in reference and foreign key order, their sequences are reset afterwards.
+func (r *DatabaseRegistry) LoadFixtures(db *gorm.DB, path string) ([]FixtureResult, error) {
+	files, err := fixtureFiles(path)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := make(map[string]*fixtureTable)
+	var names []string
+
+	for _, file := range files {
+		if err := readFixtureFile(file, tables, &names); err != nil {
+			return nil, err
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no fixtures found in %s", path)
+	}
+
+	order, err := fixtureOrder(db, tables, names)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]FixtureResult, 0, len(order))
+	err = db.Transaction(func(tx *gorm.DB) error {
+		// constraints declared deferrable may reference rows loaded later (cycles)
+		if err := tx.Exec("SET CONSTRAINTS ALL DEFERRED").Error; err != nil {
+			return err
+		}
+
+		labels := make(map[string]map[string]map[string]any)
+
+		for _, name := range order {
+			rows, err := loadFixtureTable(tx, tables[name], labels)
+			if err != nil {
+				return err
+			}
+			if err := resetSequences(tx, name); err != nil {
+				return fmt.Errorf("failed to reset sequences of table %s: %v", name, err)
+			}
+
+			results = append(results, FixtureResult{Table: name, Rows: rows})
+			slog.Info("Loaded fixtures", slog.String("table", name), slog.Int64("rows", rows))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// helper
+
+func fixtureFiles(path string) ([]string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %v", err)
+	}
+	if !info.IsDir() {
+		return []string{path}, nil
+	}
+
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read fixtures: %v", err)
+	}
+
+	var files []string
+	for _, entry := range entries {
+		// a backup directory holds a manifest next to the tables
+		if entry.IsDir() || strings.HasSuffix(entry.Name(), manifestSuffix) {
+			continue
+		}
+		if slices.Contains(fixtureExtensions, strings.ToLower(filepath.Ext(entry.Name()))) {
+			files = append(files, filepath.Join(path, entry.Name()))
+		}
+	}
+	return files, nil
+}
+
+// readFixtureFile adds the tables of a fixture file, names keeps the order tables first appear in.
+func readFixtureFile(path string, tables map[string]*fixtureTable, names *[]string) error {
+	table := func(name string) *fixtureTable {
+		if tables[name] == nil {
+			tables[name] = &fixtureTable{name: name}
+			*names = append(*names, name)
+		}
+		return tables[name]
+	}
+
+	// backups can be large, they are streamed instead of parsed as a whole
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		array, err := isJSONArray(path)
+		if err != nil {
+			return err
+		}
+		if array {
+			t := table(fixtureTableName(path))
+			t.backups = append(t.backups, path)
+			return nil
+		}
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("failed to read fixture %s: %v", path, err)
+	}
+
+	// JSON is parsed as YAML too, nodes keep the order of labeled rows
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return fmt.Errorf("failed to parse fixture %s: %v", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil
+	}
+
+	root := doc.Content[0]
+	if root.Kind != yaml.MappingNode {
+		return fmt.Errorf("fixture %s: expected tables at the top level", path)
+	}
+
+	for i := 0; i < len(root.Content); i += 2 {
+		t := table(root.Content[i].Value)
+		if err := t.add(path, root.Content[i+1]); err != nil {
+			return fmt.Errorf("fixture %s, table %s: %v", path, t.name, err)
+		}
+	}
+	return nil
+}
+
+// add appends the rows of a table node, a list of rows or a map of labeled rows and options.
+func (t *fixtureTable) add(file string, node *yaml.Node) error {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			var values map[string]any
+			if err := item.Decode(&values); err != nil {
+				return err
+			}
+			t.rows = append(t.rows, fixtureRow{file: file, values: values})
+		}
+		return nil
+
+	case yaml.MappingNode:
+		for i := 0; i < len(node.Content); i += 2 {
+			key, value := node.Content[i].Value, node.Content[i+1]
+
+			if key == "_unique" {
+				var unique []string
+				if value.Kind == yaml.ScalarNode {
+					unique = []string{value.Value}
+				} else if err := value.Decode(&unique); err != nil {
+					return fmt.Errorf("invalid _unique: %v", err)
+				}
+				if t.unique != nil && !slices.Equal(t.unique, unique) {
+					return fmt.Errorf("_unique %v differs from %v declared in another file", unique, t.unique)
+				}
+				t.unique = unique
+				continue
+			}
+
+			if slices.ContainsFunc(t.rows, func(r fixtureRow) bool { return r.label == key }) {
+				return fmt.Errorf("duplicate label %s", key)
+			}
+
+			var values map[string]any
+			if err := value.Decode(&values); err != nil {
+				return fmt.Errorf("row %s: %v", key, err)
+			}
+			t.rows = append(t.rows, fixtureRow{label: key, file: file, values: values})
+		}
+		return nil
+
+	default:
+		return fmt.Errorf("expected a list or a map of rows")
+	}
+}
+
+// fixtureOrder sorts the tables so referenced tables and foreign key parents are loaded first.
+func fixtureOrder(db *gorm.DB, tables map[string]*fixtureTable, names []string) ([]string, error) {
+	parents, err := foreignKeyParents(db, names)
+	if err != nil {
+		return nil, err
+	}
+
+	refs := make(map[string][]string)
+	for _, name := range names {
+		for _, row := range tables[name].rows {
+			for _, value := range row.values {
+				ref, ok := parseReference(value)
+				if !ok {
+					continue
+				}
+				if tables[ref.table] == nil {
+					return nil, fmt.Errorf("table %s references %s, which has no fixtures", name, ref.raw)
+				}
+				if ref.table != name && !slices.Contains(refs[name], ref.table) {
+					refs[name] = append(refs[name], ref.table)
+				}
+			}
+		}
+	}
+
+	return sortFixtures(names, refs, parents)
+}
+
+// sortFixtures orders the tables so each comes after the tables it references, which is required
+// to resolve the labels, and after its foreign key parents where possible: a foreign key cycle
+// is broken and relies on deferred constraints, a reference cycle is an error.
+func sortFixtures(names []string, refs, parents map[string][]string) ([]string, error) {
+	placed := make(map[string]bool, len(names))
+	ordered := make([]string, 0, len(names))
+
+	ready := func(name string, deps map[string][]string) bool {
+		return !slices.ContainsFunc(deps[name], func(dep string) bool {
+			return slices.Contains(names, dep) && !placed[dep]
+		})
+	}
+
+	for len(ordered) < len(names) {
+		next := slices.IndexFunc(names, func(name string) bool {
+			return !placed[name] && ready(name, refs) && ready(name, parents)
+		})
+		if next < 0 {
+			next = slices.IndexFunc(names, func(name string) bool {
+				return !placed[name] && ready(name, refs)
+			})
+		}
+		if next < 0 {
+			var waiting []string
+			for _, name := range names {
+				if !placed[name] {
+					waiting = append(waiting, name)
+				}
+			}
+			return nil, fmt.Errorf("fixtures reference each other: %s", strings.Join(waiting, ", "))
+		}
+
+		placed[names[next]] = true
+		ordered = append(ordered, names[next])
+	}
+
+	return ordered, nil
+}
+
+// loadFixtureTable upserts the backup files and the rows of a table, labeled rows are kept for references.
+func loadFixtureTable(tx *gorm.DB, t *fixtureTable, labels map[string]map[string]map[string]any) (int64, error) {
+	columns, err := tableColumns(tx, t.name)
+	if err != nil {
+		return 0, err
+	}
+	if len(columns) == 0 {
+		return 0, fmt.Errorf("fixtures of unknown table %s", t.name)
+	}
+
+	keys := t.unique
+	if len(keys) == 0 {
+		if keys, err = primaryKeys(tx, t.name); err != nil {
+			return 0, err
+		}
+	}
+	if len(keys) == 0 {
+		return 0, fmt.Errorf("table %s needs _unique or a primary key to upsert fixtures on", t.name)
+	}
+
+	var count int64
+	for _, file := range t.backups {
+		rows, err := restoreTable(tx, t.name, file, readJSONArray, RestoreOptions{BatchSize: DefaultBackupBatchSize, Upsert: true})
+		if err != nil {
+			return count, err
+		}
+		count += rows
+	}
+
+	labels[t.name] = make(map[string]map[string]any)
+	for _, row := range t.rows {
+		name := row.label
+		if name == "" {
+			name = "#" + fmt.Sprint(count+1)
+		}
+
+		values := make(map[string]any, len(row.values))
+		for key, value := range row.values {
+			column, ok := columns[key]
+			if !ok {
+				return count, fmt.Errorf("fixture %s, row %s: unknown column %s of table %s", row.file, name, key, t.name)
+			}
+			if values[column], err = resolveReference(value, labels); err != nil {
+				return count, fmt.Errorf("fixture %s, row %s: %v", row.file, name, err)
+			}
+		}
+
+		for _, key := range keys {
+			if _, ok := values[key]; !ok {
+				return count, fmt.Errorf("fixture %s, row %s: missing %s to upsert on", row.file, name, key)
+			}
+		}
+
+		saved, err := upsertFixture(tx, t.name, values, keys)
+		if err != nil {
+			return count, fmt.Errorf("fixture %s, row %s: %v", row.file, name, err)
+		}
+		if row.label != "" {
+			labels[t.name][row.label] = saved
+		}
+		count++
+	}
+
+	return count, nil
+}
+
+// upsertFixture saves one row and returns it as stored, with the generated columns.
+func upsertFixture(tx *gorm.DB, table string, row map[string]any, keys []string) (map[string]any, error) {
+	columns := make([]string, 0, len(row))
+	for column := range row {
+		columns = append(columns, column)
+	}
+	slices.Sort(columns)
+
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteIdent(c)
+	}
+	list := strings.Join(quoted, ", ")
+
+	data, err := json.Marshal(row)
+	if err != nil {
+		return nil, err
+	}
+
+	query := fmt.Sprintf("INSERT INTO %s AS fixture (%s) OVERRIDING SYSTEM VALUE SELECT %s FROM json_populate_record(NULL::%s, ?::json)%s RETURNING row_to_json(fixture.*)::text",
+		quoteIdent(table), list, list, quoteIdent(table), onConflict(columns, keys, true))
+
+	var saved string
+	if err := tx.Raw(query, string(data)).Scan(&saved).Error; err != nil {
+		return nil, err
+	}
+
+	var result map[string]any
+	dec := json.NewDecoder(strings.NewReader(saved))
+	dec.UseNumber()
+	if err := dec.Decode(&result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+type reference struct {
+	raw    string
+	table  string
+	label  string
+	column string
+}
+
+// parseReference parses `@table.label.column`.
+func parseReference(value any) (reference, bool) {
+	s, ok := value.(string)
+	if !ok || !strings.HasPrefix(s, "@") || strings.HasPrefix(s, "@@") {
+		return reference{}, false
+	}
+
+	parts := strings.Split(s[1:], ".")
+	if len(parts) != 3 {
+		return reference{}, false
+	}
+	return reference{raw: s, table: parts[0], label: parts[1], column: parts[2]}, true
+}
+
+func resolveReference(value any, labels map[string]map[string]map[string]any) (any, error) {
+	if s, ok := value.(string); ok && strings.HasPrefix(s, "@@") {
+		return s[1:], nil
+	}
+
+	ref, ok := parseReference(value)
+	if !ok {
+		return value, nil
+	}
+
+	row, ok := labels[ref.table][ref.label]
+	if !ok {
+		return nil, fmt.Errorf("unknown reference %s, rows are only referenced by label once saved", ref.raw)
+	}
+	resolved, ok := row[ref.column]
+	if !ok {
+		return nil, fmt.Errorf("unknown column in reference %s", ref.raw)
+	}
+	return resolved, nil
+}
+
+// fixtureTableName is the table of a file in the db:backup shape, `backup_<table>_<timestamp>.json` or `<table>.json`.
+func fixtureTableName(path string) string {
+	name := filepath.Base(path)
+	if table, _, _, ok := parseBackupName(name); ok {
+		return table
+	}
+	return strings.TrimSuffix(name, filepath.Ext(name))
+}
+
+func isJSONArray(path string) (bool, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, fmt.Errorf("failed to read fixture %s: %v", path, err)
+	}
+	defer file.Close()
+
+	r := bufio.NewReader(file)
+	for {
+		c, _, err := r.ReadRune()
+		if err != nil {
+			return false, nil
+		}
+		if !strings.ContainsRune(" \t\r\n\uFEFF", c) {
+			return c == '[', nil
+		}
+	}
+}
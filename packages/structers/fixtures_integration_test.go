@@ -0,0 +1,55 @@
+package structers
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadFixturesReferencingEachOther(t *testing.T) {
+	db := testDB(t)
+
+	// each table references the other, checked on commit only once deferred
+	err := db.Exec(`CREATE TABLE teams (id int PRIMARY KEY, name text, captain_id int);
+		CREATE TABLE players (id int PRIMARY KEY, name text, team_id int REFERENCES teams DEFERRABLE);
+		ALTER TABLE teams ADD FOREIGN KEY (captain_id) REFERENCES players DEFERRABLE`).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := filepath.Join(t.TempDir(), "teams.yaml")
+	fixtures := `teams:
+  red: {id: 1, name: Red, captain_id: 10}
+players:
+  ada: {id: 10, name: Ada, team_id: 1}
+  alan: {id: 11, name: Alan, team_id: 1}
+`
+	if err := os.WriteFile(path, []byte(fixtures), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	registry := &DatabaseRegistry{}
+	results, err := registry.LoadFixtures(db, path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 2 {
+		t.Fatalf("results = %+v, want both tables", results)
+	}
+
+	var captain string
+	if err := db.Raw("SELECT p.name FROM teams t JOIN players p ON p.id = t.captain_id WHERE t.id = 1").Scan(&captain).Error; err != nil {
+		t.Fatal(err)
+	}
+	if captain != "Ada" {
+		t.Errorf("captain = %q, want Ada", captain)
+	}
+
+	// a dangling key still fails on commit
+	if err := os.WriteFile(path, []byte("players:\n  - {id: 12, name: Grace, team_id: 2}\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := registry.LoadFixtures(db, path); err == nil {
+		t.Error("fixtures referencing a missing team were loaded")
+	}
+}
@@ -0,0 +1,145 @@
+package structers
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseReference(t *testing.T) {
+	tests := []struct {
+		value any
+		want  reference
+		ok    bool
+	}{
+		{value: "@users.admin.id", want: reference{raw: "@users.admin.id", table: "users", label: "admin", column: "id"}, ok: true},
+		{value: "@plans.pro.code", want: reference{raw: "@plans.pro.code", table: "plans", label: "pro", column: "code"}, ok: true},
+		{value: "@@users.admin.id", ok: false},
+		{value: "@users.admin", ok: false},
+		{value: "@public.users.admin.id", ok: false},
+		{value: "users.admin.id", ok: false},
+		{value: "admin@example.com", ok: false},
+		{value: 42, ok: false},
+		{value: nil, ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(fmt.Sprint(tt.value), func(t *testing.T) {
+			got, ok := parseReference(tt.value)
+			if ok != tt.ok || got != tt.want {
+				t.Errorf("parseReference(%v) = %+v, %v, want %+v, %v", tt.value, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
+
+func TestResolveReference(t *testing.T) {
+	labels := map[string]map[string]map[string]any{
+		"users": {"admin": {"id": int64(7), "email": "admin@example.com"}},
+	}
+
+	tests := []struct {
+		name    string
+		value   any
+		want    any
+		wantErr string
+	}{
+		{name: "reference", value: "@users.admin.id", want: int64(7)},
+		{name: "escaped", value: "@@users.admin.id", want: "@users.admin.id"},
+		{name: "plain value", value: "admin", want: "admin"},
+		{name: "unknown label", value: "@users.guest.id", wantErr: "unknown reference"},
+		{name: "unknown column", value: "@users.admin.name", wantErr: "unknown column"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := resolveReference(tt.value, labels)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got != tt.want {
+				t.Errorf("resolved %v to %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSortFixtures(t *testing.T) {
+	tests := []struct {
+		name    string
+		names   []string
+		refs    map[string][]string
+		parents map[string][]string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:  "file order without dependencies",
+			names: []string{"plans", "users"},
+			want:  []string{"plans", "users"},
+		},
+		{
+			name:  "referenced tables first",
+			names: []string{"subscriptions", "users", "plans"},
+			refs:  map[string][]string{"subscriptions": {"users", "plans"}},
+			want:  []string{"users", "plans", "subscriptions"},
+		},
+		{
+			name:    "a reference doesn't pull a table ahead of its foreign key parent",
+			names:   []string{"invoices", "subscriptions", "accounts"},
+			refs:    map[string][]string{"invoices": {"subscriptions"}},
+			parents: map[string][]string{"subscriptions": {"accounts"}},
+			want:    []string{"accounts", "subscriptions", "invoices"},
+		},
+		{
+			name:    "foreign key cycle is broken",
+			names:   []string{"a", "b"},
+			parents: map[string][]string{"a": {"b"}, "b": {"a"}},
+			want:    []string{"a", "b"},
+		},
+		{
+			name:    "references win over a foreign key cycle",
+			names:   []string{"a", "b"},
+			refs:    map[string][]string{"a": {"b"}},
+			parents: map[string][]string{"a": {"b"}, "b": {"a"}},
+			want:    []string{"b", "a"},
+		},
+		{
+			name:    "parents without fixtures are ignored",
+			names:   []string{"orders"},
+			parents: map[string][]string{"orders": {"users"}},
+			want:    []string{"orders"},
+		},
+		{
+			name:    "reference cycle",
+			names:   []string{"a", "b", "c"},
+			refs:    map[string][]string{"a": {"b"}, "b": {"a"}},
+			wantErr: "fixtures reference each other: a, b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := sortFixtures(tt.names, tt.refs, tt.parents)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,302 @@
+package structers
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Watermark is the highest value of the column tracking changes of a table when it was backed up.
+type Watermark struct {
+	Column string  `json:"column"`
+	Type   string  `json:"type"`
+	Value  *string `json:"value"` // nil for empty tables
+}
+
+// backupRun is a backup run opened for reading, archives are extracted to a temporary directory.
+type backupRun struct {
+	manifest *BackupManifest   // nil for backups written before manifests
+	files    map[string]string // table to file path
+	cleanup  func()
+}
+
+// incremental reports whether the run only holds the rows of table changed since its parent.
+func (b *backupRun) incremental(table string) bool {
+	if b.manifest == nil {
+		return false
+	}
+	for _, t := range b.manifest.Tables {
+		if t.Table == table {
+			return t.Incremental
+		}
+	}
+	return false
+}
+
+// helper
+
+// tableWatermark reads the current maximum of `updated_at`, or else of a single integer primary key.
+// Tables without either, or without primary key, have no watermark and are always exported in full.
+func tableWatermark(db *gorm.DB, table string, columns []ManifestColumn) (*Watermark, error) {
+	var column string
+	var keys []string
+
+	for _, c := range columns {
+		if c.Name == "updated_at" {
+			column = c.Name
+		}
+		if c.PrimaryKey {
+			keys = append(keys, c.Name)
+		}
+	}
+
+	// changed rows are upserted on restore, which needs a primary key
+	if len(keys) == 0 {
+		return nil, nil
+	}
+	if column == "" && len(keys) == 1 {
+		column = keys[0]
+	}
+	if column == "" {
+		return nil, nil
+	}
+
+	var dataType string
+	err := db.Raw(`SELECT format_type(atttypid, atttypmod) FROM pg_attribute
+		WHERE attrelid = to_regclass(?) AND attname = ? AND NOT attisdropped`, quoteIdent(table), column).Scan(&dataType).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// keys like uuids don't grow, only integer keys can tell new rows apart
+	if column != "updated_at" && !slices.Contains([]string{"smallint", "integer", "bigint"}, dataType) {
+		return nil, nil
+	}
+
+	var value *string
+	query := fmt.Sprintf("SELECT MAX(%s)::text FROM %s", quoteIdent(column), quoteIdent(table))
+	if err := db.Raw(query).Scan(&value).Error; err != nil {
+		return nil, err
+	}
+
+	return &Watermark{Column: column, Type: dataType, Value: value}, nil
+}
+
+// changedSince scopes the export of a table to the rows changed since the parent run,
+// ok is false when the table has to be exported in full. Rows without a watermark value
+// can't be told apart and are exported every time, deleted rows are never captured.
+func changedSince(parent *BackupManifest, table string, current *Watermark) (scope func(*gorm.DB) *gorm.DB, ok bool) {
+	if parent == nil || current == nil {
+		return nil, false
+	}
+
+	idx := slices.IndexFunc(parent.Tables, func(t ManifestTable) bool { return t.Table == table })
+	if idx < 0 {
+		return nil, false
+	}
+
+	previous := parent.Tables[idx].Watermark
+	if previous == nil || previous.Value == nil || previous.Column != current.Column {
+		return nil, false
+	}
+
+	// rows sharing the previous maximum are exported again, restoring them is an upsert
+	column := quoteIdent(previous.Column)
+	condition := fmt.Sprintf("(%s >= ?::%s OR %s IS NULL)", column, previous.Type, column)
+	return func(q *gorm.DB) *gorm.DB {
+		return q.Where(condition, *previous.Value)
+	}, true
+}
+
+// parentMismatch tells why a run can't be the parent of an incremental backup of tables,
+// empty when it was taken with the same profile, tables and row filters.
+func parentMismatch(parent *BackupManifest, profile string, tables []string, where map[string]string) string {
+	if parent.Profile != profile {
+		return fmt.Sprintf("profile %q instead of %q", parent.Profile, profile)
+	}
+
+	previous := make([]string, len(parent.Tables))
+	for i, t := range parent.Tables {
+		previous[i] = t.Table
+	}
+	slices.Sort(previous)
+	if current := slices.Sorted(slices.Values(tables)); !slices.Equal(previous, current) {
+		return fmt.Sprintf("tables %s instead of %s", strings.Join(previous, ", "), strings.Join(current, ", "))
+	}
+
+	for _, t := range parent.Tables {
+		if t.Where != where[t.Table] {
+			return fmt.Sprintf("table %s filtered by %q instead of %q", t.Table, t.Where, where[t.Table])
+		}
+	}
+	return ""
+}
+
+// latestManifest returns the manifest of the newest backup run in root, or nil when there is none.
+func latestManifest(root string, key []byte) (*BackupManifest, error) {
+	if _, err := os.Stat(root); os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	runs, err := ListBackups(root)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, run := range runs {
+		manifest, err := runManifest(run, key)
+		if err != nil || manifest != nil {
+			return manifest, err
+		}
+	}
+
+	return nil, nil
+}
+
+// runManifest reads the manifest of a run from its manifest file or archive, nil when it has none.
+func runManifest(run BackupRun, key []byte) (*BackupManifest, error) {
+	for _, file := range run.Files {
+		name := filepath.Base(file)
+
+		switch {
+		case strings.HasSuffix(name, manifestSuffix):
+			return readManifestFile(file)
+		case isArchive(name):
+			return readArchiveManifest(file, key)
+		}
+	}
+	return nil, nil
+}
+
+// openBackup opens the backup run given as input, a directory or an archive.
+func (r *DatabaseRegistry) openBackup(input string, key []byte) (*backupRun, error) {
+	dir, cleanup, err := r.backupInput(input, key)
+	if err != nil {
+		return nil, err
+	}
+
+	path, err := manifestPath(dir)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+
+	run, err := r.openRun(dir, path)
+	if err != nil {
+		cleanup()
+		return nil, err
+	}
+	run.cleanup = cleanup
+	return run, nil
+}
+
+// findRun opens the run with the given id in root or one of its dated subdirectories.
+func (r *DatabaseRegistry) findRun(root, id string, key []byte) (*backupRun, error) {
+	runs, err := ListBackups(root)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, run := range runs {
+		if run.ID != id {
+			continue
+		}
+
+		for _, file := range run.Files {
+			name := filepath.Base(file)
+
+			if strings.HasSuffix(name, manifestSuffix) {
+				return r.openRun(filepath.Dir(file), file)
+			}
+
+			if isArchive(name) {
+				return r.openBackup(file, key)
+			}
+		}
+	}
+
+	return nil, fmt.Errorf("backup %s not found in %s", id, root)
+}
+
+// backupChain opens the run given as input preceded by the runs it is based on, the full backup first.
+func (r *DatabaseRegistry) backupChain(input string, key []byte) ([]*backupRun, error) {
+	run, err := r.openBackup(input, key)
+	if err != nil {
+		return nil, err
+	}
+	chain := []*backupRun{run}
+
+	// parents are looked up next to the dated folder of the input
+	root := filepath.Dir(filepath.Clean(input))
+	if info, err := os.Stat(input); err == nil && !info.IsDir() {
+		root = filepath.Dir(root)
+	}
+
+	// a run based on itself or on one of its descendants would be replayed forever
+	visited := make(map[string]bool)
+	for run.manifest != nil && run.manifest.Type == BackupIncremental {
+		visited[run.manifest.Run()] = true
+		if visited[run.manifest.Parent] {
+			closeChain(chain)
+			return nil, fmt.Errorf("backup %s is based on %s, which is already part of its chain", run.manifest.Run(), run.manifest.Parent)
+		}
+
+		parent, err := r.findRun(root, run.manifest.Parent, key)
+		if err != nil {
+			closeChain(chain)
+			return nil, fmt.Errorf("failed to find the parent of backup %s: %v", run.manifest.Run(), err)
+		}
+
+		slog.Info("Replaying incremental backup", slog.String("run", run.manifest.Run()), slog.String("parent", run.manifest.Parent))
+		chain = append([]*backupRun{parent}, chain...)
+		run = parent
+	}
+
+	return chain, nil
+}
+
+func closeChain(chain []*backupRun) {
+	for _, run := range chain {
+		if run.cleanup != nil {
+			run.cleanup()
+		}
+	}
+}
+
+// openRun lists the files of a run, from its manifest when there is one,
+// otherwise the newest file of each registered table in dir.
+func (r *DatabaseRegistry) openRun(dir, manifestFile string) (*backupRun, error) {
+	run := &backupRun{cleanup: func() {}}
+
+	if manifestFile == "" {
+		files, err := r.LatestBackups(dir)
+		if err != nil {
+			return nil, err
+		}
+		run.files = files
+		return run, nil
+	}
+
+	manifest, err := readManifestFile(manifestFile)
+	if err != nil {
+		return nil, err
+	}
+	run.manifest = manifest
+	run.files = make(map[string]string)
+
+	for _, t := range manifest.Tables {
+		_, _, ext, _ := parseBackupName(t.File)
+		if format, ok := r.formatByExtension(ext); !ok || format.Reader == nil {
+			continue
+		}
+
+		run.files[t.Table] = filepath.Join(dir, filepath.Base(t.File))
+	}
+
+	return run, nil
+}
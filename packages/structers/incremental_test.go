@@ -0,0 +1,197 @@
+package structers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBackupChain(t *testing.T) {
+	type run struct {
+		dir, id, parent string
+	}
+
+	tests := []struct {
+		name    string
+		runs    []run
+		input   string // dir of the run restored
+		want    []string
+		wantErr string
+	}{
+		{
+			name:  "full backup",
+			runs:  []run{{"20250101", "20250101010000", ""}},
+			input: "20250101",
+			want:  []string{"20250101010000"},
+		},
+		{
+			name: "incrementals across days",
+			runs: []run{
+				{"20250101", "20250101010000", ""},
+				{"20250102", "20250102010000", "20250101010000"},
+				{"20250103", "20250103010000", "20250102010000"},
+			},
+			input: "20250103",
+			want:  []string{"20250101010000", "20250102010000", "20250103010000"},
+		},
+		{
+			name: "legacy minute ids",
+			runs: []run{
+				{"20250101", "202501010100", ""},
+				{"20250102", "20250102010000", "202501010100"},
+			},
+			input: "20250102",
+			want:  []string{"202501010100", "20250102010000"},
+		},
+		{
+			name:    "based on itself",
+			runs:    []run{{"20250101", "20250101010000", "20250101010000"}},
+			input:   "20250101",
+			wantErr: "already part of its chain",
+		},
+		{
+			name: "cycle",
+			runs: []run{
+				{"20250101", "20250101010000", "20250102010000"},
+				{"20250102", "20250102010000", "20250101010000"},
+			},
+			input:   "20250102",
+			wantErr: "already part of its chain",
+		},
+		{
+			name:    "missing parent",
+			runs:    []run{{"20250102", "20250102010000", "20250101010000"}},
+			input:   "20250102",
+			wantErr: "not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := t.TempDir()
+			for _, r := range tt.runs {
+				dir := filepath.Join(root, r.dir)
+				if err := os.MkdirAll(dir, 0755); err != nil {
+					t.Fatal(err)
+				}
+
+				manifest := &BackupManifest{ID: r.id, Timestamp: time.Now(), Format: "json", Type: BackupFull, Parent: r.parent}
+				if r.parent != "" {
+					manifest.Type = BackupIncremental
+				}
+				path := filepath.Join(dir, fmt.Sprintf("backup_%s%s", r.id, manifestSuffix))
+				if err := writeManifest(path, manifest); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			registry := &DatabaseRegistry{}
+			chain, err := registry.backupChain(filepath.Join(root, tt.input), nil)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer closeChain(chain)
+
+			var got []string
+			for _, run := range chain {
+				got = append(got, run.manifest.Run())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("chain = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParentMismatch(t *testing.T) {
+	parent := &BackupManifest{
+		ID: "20250101010000",
+		Tables: []ManifestTable{
+			{Table: "orders", Where: "created_at > '2024-01-01'"},
+			{Table: "users"},
+		},
+	}
+
+	tests := []struct {
+		name    string
+		profile string
+		tables  []string
+		where   map[string]string
+		want    string
+	}{
+		{
+			name:   "same tables and filters",
+			tables: []string{"users", "orders"},
+			where:  map[string]string{"orders": "created_at > '2024-01-01'"},
+		},
+		{
+			name:    "other profile",
+			profile: "anonymized",
+			tables:  []string{"orders", "users"},
+			where:   map[string]string{"orders": "created_at > '2024-01-01'"},
+			want:    `profile "" instead of "anonymized"`,
+		},
+		{
+			name:   "table added",
+			tables: []string{"orders", "users", "items"},
+			where:  map[string]string{"orders": "created_at > '2024-01-01'"},
+			want:   "tables orders, users instead of items, orders, users",
+		},
+		{
+			name:   "table removed",
+			tables: []string{"users"},
+			want:   "tables orders, users instead of users",
+		},
+		{
+			name:   "other filter",
+			tables: []string{"orders", "users"},
+			where:  map[string]string{"orders": "created_at > '2025-01-01'"},
+			want:   `table orders filtered by "created_at > '2024-01-01'" instead of "created_at > '2025-01-01'"`,
+		},
+		{
+			name:   "filter removed",
+			tables: []string{"orders", "users"},
+			want:   `table orders filtered by "created_at > '2024-01-01'" instead of ""`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parentMismatch(parent, tt.profile, tt.tables, tt.where); got != tt.want {
+				t.Errorf("mismatch = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRunID(t *testing.T) {
+	tests := []struct {
+		id   string
+		want time.Time
+		ok   bool
+	}{
+		{id: "20250518013045", want: time.Date(2025, 5, 18, 1, 30, 45, 0, time.Local), ok: true},
+		{id: "202505180130", want: time.Date(2025, 5, 18, 1, 30, 0, 0, time.Local), ok: true},
+		{id: "2025051801", ok: false},
+		{id: "20251318013045", ok: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.id, func(t *testing.T) {
+			got, ok := parseRunID(tt.id)
+			if ok != tt.ok || !got.Equal(tt.want) {
+				t.Errorf("parseRunID(%q) = %v, %v, want %v, %v", tt.id, got, ok, tt.want, tt.ok)
+			}
+		})
+	}
+}
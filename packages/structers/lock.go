@@ -0,0 +1,56 @@
+package structers
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// migrationLockKey identifies the advisory lock shared by every process running migrations.
+const migrationLockKey int64 = 0x6d696772617465 // "migrate"
+
+const (
+	DefaultLockTimeout = time.Minute
+	lockRetryInterval  = 500 * time.Millisecond
+)
+
+var ErrMigrationLocked = errors.New("migration lock is held by another process")
+
+// acquireMigrationLock takes a transaction level advisory lock, so it is released on commit or rollback.
+// It polls until the lock is free or the timeout elapses, a zero timeout uses DefaultLockTimeout.
+func acquireMigrationLock(tx *gorm.DB, timeout time.Duration) error {
+	if timeout <= 0 {
+		timeout = DefaultLockTimeout
+	}
+
+	deadline := time.Now().Add(timeout)
+	waiting := false
+
+	for {
+		var acquired bool
+		if err := tx.Raw("SELECT pg_try_advisory_xact_lock(?)", migrationLockKey).Scan(&acquired).Error; err != nil {
+			return fmt.Errorf("failed to acquire migration lock: %v", err)
+		}
+
+		if acquired {
+			return nil
+		}
+
+		if time.Now().After(deadline) {
+			var pid int
+			tx.Raw(`SELECT pid FROM pg_locks WHERE locktype = 'advisory' AND granted
+				AND ((classid::bigint << 32) | objid::bigint) = ? LIMIT 1`, migrationLockKey).Scan(&pid)
+			return fmt.Errorf("%w (pid %d), gave up after %s", ErrMigrationLocked, pid, timeout)
+		}
+
+		if !waiting {
+			slog.Info("Waiting for migration lock...", slog.Duration("timeout", timeout))
+			waiting = true
+		}
+
+		time.Sleep(lockRetryInterval)
+	}
+}
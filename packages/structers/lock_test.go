@@ -0,0 +1,35 @@
+package structers
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMigrationLock(t *testing.T) {
+	db := testDB(t)
+
+	// the first migrator holds the lock until its transaction ends
+	first := db.Begin()
+	if err := acquireMigrationLock(first, time.Second); err != nil {
+		first.Rollback()
+		t.Fatal(err)
+	}
+
+	second := &DatabaseRegistry{Migrations: t.TempDir(), LockTimeout: 100 * time.Millisecond}
+	err := second.Migrate(db, false)
+	if !errors.Is(err, ErrMigrationLocked) {
+		first.Rollback()
+		t.Fatalf("second migration error = %v, want %v", err, ErrMigrationLocked)
+	}
+	if db.Migrator().HasTable(&SchemaMigration{}) {
+		t.Error("the locked out migration created the schema migrations table")
+	}
+
+	if err := first.Rollback().Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := second.Migrate(db, false); err != nil {
+		t.Errorf("migration after the lock was released: %v", err)
+	}
+}
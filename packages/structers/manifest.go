@@ -0,0 +1,256 @@
+package structers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// archives hold a single run described by `manifest.json`, runs sharing a directory
+// are described by `backup_<timestamp>.manifest.json` each
+const (
+	manifestName   = "manifest.json"
+	manifestSuffix = ".manifest.json"
+)
+
+const (
+	BackupFull        = "full"
+	BackupIncremental = "incremental"
+)
+
+// runIDLayout is the timestamp identifying a run, runs written before it had seconds use legacyRunIDLayout.
+const (
+	runIDLayout       = "20060102150405"
+	legacyRunIDLayout = "200601021504"
+)
+
+// BackupManifest describes a backup run.
+type BackupManifest struct {
+	ID        string          `json:"id,omitempty"` // run identifier, empty in manifests written before it
+	Version   any             `json:"version"`      // the application VersionInfo
+	Timestamp time.Time       `json:"timestamp"`
+	Format    string          `json:"format"`
+	Type      string          `json:"type"`              // full or incremental
+	Parent    string          `json:"parent,omitempty"`  // run the incremental backup is based on
+	Profile   string          `json:"profile,omitempty"` // anonymized when columns were masked
+	Tables    []ManifestTable `json:"tables"`
+}
+
+// ManifestTable is the backup file of one table with its row count, checksum and schema.
+type ManifestTable struct {
+	Table       string           `json:"table"`
+	File        string           `json:"file"`
+	Rows        int64            `json:"rows"`
+	SHA256      string           `json:"sha256"`
+	Columns     []ManifestColumn `json:"columns"`
+	Watermark   *Watermark       `json:"watermark,omitempty"`
+	Incremental bool             `json:"incremental,omitempty"` // only rows changed since the parent run
+	Where       string           `json:"where,omitempty"`       // condition the rows were filtered with
+}
+
+// ManifestColumn is a column definition as reported by the GORM migrator.
+type ManifestColumn struct {
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	Nullable   bool    `json:"nullable"`
+	PrimaryKey bool    `json:"primaryKey"`
+	Default    *string `json:"default,omitempty"`
+}
+
+// TableVerification is the result of checking one manifest table against its backup file.
+type TableVerification struct {
+	Table    string
+	File     string
+	Rows     int64 // rows read back, -1 when the format has no reader
+	Problems []string
+}
+
+func (v *TableVerification) OK() bool {
+	return len(v.Problems) == 0
+}
+
+// Run is the identifier of the backup run, as used in file names and by Parent.
+func (m *BackupManifest) Run() string {
+	if m.ID != "" {
+		return m.ID
+	}
+	return m.Timestamp.Format(legacyRunIDLayout)
+}
+
+// ReadManifest reads the manifest of a backup directory, the newest one when several runs share it.
+func ReadManifest(dir string) (*BackupManifest, error) {
+	path, err := manifestPath(dir)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, fmt.Errorf("no manifest found in %s", dir)
+	}
+	return readManifestFile(path)
+}
+
+// VerifyBackup checks every file listed in the manifest of a backup directory or archive
+// against its SHA-256 checksum and, when the format can be read back, its row count.
+func (r *DatabaseRegistry) VerifyBackup(input string, key []byte) (*BackupManifest, []TableVerification, error) {
+	dir, cleanup, err := r.backupInput(input, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cleanup()
+
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	results := make([]TableVerification, 0, len(manifest.Tables))
+	for _, table := range manifest.Tables {
+		results = append(results, r.verifyTable(dir, table))
+	}
+
+	return manifest, results, nil
+}
+
+// helper
+
+// manifestPath finds `manifest.json` or the newest run manifest in dir, empty when there is none.
+func manifestPath(dir string) (string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return "", fmt.Errorf("failed to read backup directory: %v", err)
+	}
+
+	var latest string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() {
+			continue
+		}
+		if name == manifestName {
+			return filepath.Join(dir, name), nil
+		}
+		if strings.HasPrefix(name, "backup_") && strings.HasSuffix(name, manifestSuffix) && name > latest {
+			latest = name
+		}
+	}
+
+	if latest == "" {
+		return "", nil
+	}
+	return filepath.Join(dir, latest), nil
+}
+
+func readManifestFile(path string) (*BackupManifest, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read manifest: %v", err)
+	}
+
+	var manifest BackupManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("failed to parse manifest: %v", err)
+	}
+	return &manifest, nil
+}
+
+func writeManifest(path string, manifest *BackupManifest) error {
+	data, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode manifest: %v", err)
+	}
+
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write manifest: %v", err)
+	}
+	return nil
+}
+
+// manifestColumns reads the column definitions of a table through the migrator.
+func manifestColumns(db *gorm.DB, table string) ([]ManifestColumn, error) {
+	columnTypes, err := db.Migrator().ColumnTypes(table)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]ManifestColumn, 0, len(columnTypes))
+	for _, ct := range columnTypes {
+		column := ManifestColumn{Name: ct.Name(), Type: ct.DatabaseTypeName()}
+		if full, ok := ct.ColumnType(); ok && full != "" {
+			column.Type = full
+		}
+		if nullable, ok := ct.Nullable(); ok {
+			column.Nullable = nullable
+		}
+		if primary, ok := ct.PrimaryKey(); ok {
+			column.PrimaryKey = primary
+		}
+		if value, ok := ct.DefaultValue(); ok {
+			column.Default = &value
+		}
+		columns = append(columns, column)
+	}
+	return columns, nil
+}
+
+func (r *DatabaseRegistry) verifyTable(dir string, table ManifestTable) TableVerification {
+	result := TableVerification{Table: table.Table, File: table.File, Rows: -1}
+
+	// manifests only reference files of their own directory
+	path := filepath.Join(dir, filepath.Base(table.File))
+
+	checksum, err := fileChecksum(path)
+	if err != nil {
+		result.Problems = append(result.Problems, err.Error())
+		return result
+	}
+	if checksum != table.SHA256 {
+		result.Problems = append(result.Problems, "checksum mismatch")
+	}
+
+	_, _, ext, _ := parseBackupName(table.File)
+	format, ok := r.formatByExtension(ext)
+	if !ok || format.Reader == nil {
+		return result
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		result.Problems = append(result.Problems, err.Error())
+		return result
+	}
+	defer file.Close()
+
+	var rows int64
+	if err := format.Reader(file, func(map[string]any) error { rows++; return nil }); err != nil {
+		result.Problems = append(result.Problems, fmt.Sprintf("unreadable: %v", err))
+		return result
+	}
+
+	result.Rows = rows
+	if rows != table.Rows {
+		result.Problems = append(result.Problems, fmt.Sprintf("expected %d rows, found %d", table.Rows, rows))
+	}
+	return result
+}
+
+func fileChecksum(path string) (string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("missing file %s", filepath.Base(path))
+	}
+	defer file.Close()
+
+	hash := sha256.New()
+	if _, err := io.Copy(hash, file); err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", filepath.Base(path), err)
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
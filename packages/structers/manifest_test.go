@@ -0,0 +1,168 @@
+package structers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestVerifyBackup(t *testing.T) {
+	const (
+		users  = `[{"id": 1}, {"id": 2}]`
+		orders = `{"id": 1}` + "\n" + `{"id": 2}` + "\n" + `{"id": 3}` + "\n"
+	)
+
+	tests := []struct {
+		name     string
+		files    map[string]string // overrides of the written files, a removed file is empty
+		tables   func([]ManifestTable)
+		problems map[string][]string
+	}{
+		{
+			name:     "intact",
+			problems: map[string][]string{},
+		},
+		{
+			name:     "checksum mismatch",
+			files:    map[string]string{"backup_users_20250101000000.json": `[{"id": 1}, {"id": 3}]`},
+			problems: map[string][]string{"users": {"checksum mismatch"}},
+		},
+		{
+			name:     "row count mismatch",
+			tables:   func(tables []ManifestTable) { tables[1].Rows = 4 },
+			problems: map[string][]string{"orders": {"expected 4 rows, found 3"}},
+		},
+		{
+			name:     "missing file",
+			files:    map[string]string{"backup_orders_20250101000000.ndjson": ""},
+			problems: map[string][]string{"orders": {"missing file backup_orders_20250101000000.ndjson"}},
+		},
+		{
+			name:     "unreadable file",
+			files:    map[string]string{"backup_users_20250101000000.json": `[{"id": 1}`},
+			problems: map[string][]string{"users": {"checksum mismatch", "unreadable"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			files := map[string]string{
+				"backup_users_20250101000000.json":    users,
+				"backup_orders_20250101000000.ndjson": orders,
+			}
+
+			manifest := &BackupManifest{ID: "20250101000000", Timestamp: time.Now(), Format: "json", Type: BackupFull}
+			for _, name := range []string{"backup_users_20250101000000.json", "backup_orders_20250101000000.ndjson"} {
+				path := filepath.Join(dir, name)
+				if err := os.WriteFile(path, []byte(files[name]), 0644); err != nil {
+					t.Fatal(err)
+				}
+				checksum, err := fileChecksum(path)
+				if err != nil {
+					t.Fatal(err)
+				}
+				table, _, _, _ := parseBackupName(name)
+				manifest.Tables = append(manifest.Tables, ManifestTable{Table: table, File: name, Rows: int64(strings.Count(files[name], `"id"`)), SHA256: checksum})
+			}
+			if tt.tables != nil {
+				tt.tables(manifest.Tables)
+			}
+			if err := writeManifest(filepath.Join(dir, "backup_20250101000000"+manifestSuffix), manifest); err != nil {
+				t.Fatal(err)
+			}
+
+			for name, content := range tt.files {
+				path := filepath.Join(dir, name)
+				if content == "" {
+					os.Remove(path)
+				} else if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			registry := &DatabaseRegistry{}
+			read, results, err := registry.VerifyBackup(dir, nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if read.Run() != manifest.Run() || len(results) != 2 {
+				t.Fatalf("verified run %s with %d results, want run %s with 2", read.Run(), len(results), manifest.Run())
+			}
+
+			for _, result := range results {
+				want := tt.problems[result.Table]
+				if len(result.Problems) != len(want) {
+					t.Errorf("%s problems = %q, want %q", result.Table, result.Problems, want)
+					continue
+				}
+				for i, problem := range want {
+					if !strings.HasPrefix(result.Problems[i], problem) {
+						t.Errorf("%s problems = %q, want %q", result.Table, result.Problems, want)
+					}
+				}
+				if result.OK() != (len(want) == 0) {
+					t.Errorf("%s OK = %v with problems %q", result.Table, result.OK(), result.Problems)
+				}
+			}
+		})
+	}
+}
+
+func TestReadManifestNewestRun(t *testing.T) {
+	dir := t.TempDir()
+	for _, id := range []string{"20250101000000", "20250102000000", "202412312359"} {
+		manifest := &BackupManifest{ID: id, Format: "json", Type: BackupFull}
+		if err := writeManifest(filepath.Join(dir, "backup_"+id+manifestSuffix), manifest); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	manifest, err := ReadManifest(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if manifest.Run() != "20250102000000" {
+		t.Errorf("read run %s, want 20250102000000", manifest.Run())
+	}
+
+	if _, err := ReadManifest(t.TempDir()); err == nil || !strings.Contains(err.Error(), "no manifest found") {
+		t.Errorf("error = %v, want no manifest found", err)
+	}
+}
+
+func TestManifestRoundTrip(t *testing.T) {
+	value := "0"
+	manifest := &BackupManifest{
+		ID:        "20250101000000",
+		Version:   map[string]any{"version": "1.0.0"},
+		Timestamp: time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC),
+		Format:    "json",
+		Type:      BackupFull,
+		Tables: []ManifestTable{{
+			Table:  "users",
+			File:   "backup_users_20250101000000.json",
+			Rows:   2,
+			SHA256: "abc",
+			Columns: []ManifestColumn{
+				{Name: "id", Type: "bigint", PrimaryKey: true},
+				{Name: "visits", Type: "integer", Nullable: true, Default: &value},
+			},
+		}},
+	}
+
+	path := filepath.Join(t.TempDir(), manifestName)
+	if err := writeManifest(path, manifest); err != nil {
+		t.Fatal(err)
+	}
+	read, err := readManifestFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(read, manifest) {
+		t.Errorf("read %+v, want %+v", read, manifest)
+	}
+}
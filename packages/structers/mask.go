@@ -0,0 +1,229 @@
+package structers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	cipher "webservices/packages/chiper"
+	"webservices/packages/faker"
+)
+
+// mask types
+const (
+	MaskHash     = "hash"     // keyed hash of 16 characters, uuids stay uuids, integers are permuted within their range and sign
+	MaskFake     = "fake"     // realistic value of the faker generator Mask.Fake: name, email, phone, address, company, date...
+	MaskNull     = "null"     // NULL
+	MaskEmail    = "email"    // hash of the local part, the domain is kept
+	MaskTruncate = "truncate" // the first Mask.Length characters, 1 by default
+)
+
+// BackupProfileAnonymized applies `Database.Masks` to the exported rows.
+const BackupProfileAnonymized = "anonymized"
+
+// Mask rewrites a column in anonymized backups, register them per table and column in `Database.Masks`.
+// Values are derived from a subkey of the application KEY and the original value only,
+// so the same value is masked the same way in every table and foreign keys keep matching.
+// Masked text is cut to the length of character columns.
+type Mask struct {
+	Type   string
+	Fake   string
+	Length int
+
+	limit int // length of the character column, 0 when unlimited
+}
+
+// validate checks the mask is known.
+func (m Mask) validate() error {
+	switch m.Type {
+	case MaskHash, MaskNull, MaskEmail, MaskTruncate:
+		return nil
+	case MaskFake:
+		if !faker.Has(m.Fake) {
+			return fmt.Errorf("unknown fake %q, expected one of %v", m.Fake, faker.Generators())
+		}
+		return nil
+	default:
+		return fmt.Errorf("unknown mask %q", m.Type)
+	}
+}
+
+// apply masks a scanned value, NULL stays NULL.
+func (m Mask) apply(key []byte, value any) any {
+	masked := m.mask(key, value)
+	if text, ok := masked.(string); ok && m.limit > 0 {
+		if runes := []rune(text); len(runes) > m.limit {
+			return string(runes[:m.limit])
+		}
+	}
+	return masked
+}
+
+func (m Mask) mask(key []byte, value any) any {
+	if value == nil || m.Type == MaskNull {
+		return nil
+	}
+
+	switch m.Type {
+	case MaskHash:
+		return hashValue(key, value)
+	case MaskFake:
+		fake, _ := maskFaker(key, textValue(value)).Generate(m.Fake)
+		return fake
+	case MaskEmail:
+		local, domain, found := strings.Cut(textValue(value), "@")
+		if !found {
+			return hashText(key, local)
+		}
+		return hashText(key, local)[:12] + "@" + domain
+	case MaskTruncate:
+		length := max(m.Length, 1)
+		runes := []rune(textValue(value))
+		if len(runes) > length {
+			runes = runes[:length]
+		}
+		return string(runes)
+	}
+	return value
+}
+
+// helper
+
+// backupMasks returns the masks of the backup profile, keyed by table and column,
+// and the masking subkey of the application KEY.
+func (r *DatabaseRegistry) backupMasks(profile string, key []byte) (map[string]map[string]Mask, []byte, error) {
+	switch profile {
+	case "":
+		return nil, nil, nil
+	case BackupProfileAnonymized:
+	default:
+		return nil, nil, fmt.Errorf("unknown backup profile %q", profile)
+	}
+
+	if len(key) == 0 {
+		return nil, nil, fmt.Errorf("anonymized backups need the application KEY, run `generate:key` first")
+	}
+
+	for table, columns := range r.Masks {
+		for column, mask := range columns {
+			if err := mask.validate(); err != nil {
+				return nil, nil, fmt.Errorf("invalid mask of %s.%s: %v", table, column, err)
+			}
+		}
+	}
+
+	maskKey, err := cipher.DeriveKey(key, cipher.PurposeMask)
+	if err != nil {
+		return nil, nil, err
+	}
+	return r.Masks, maskKey, nil
+}
+
+var characterType = regexp.MustCompile(`^(?:character varying|varchar|character|char|bpchar)\((\d+)\)$`)
+
+// fitMasks returns the masks of a table limited to the length of their character columns,
+// a 16 characters hash or a fake value would not fit a varchar(8).
+func fitMasks(masks map[string]Mask, columns []ManifestColumn) map[string]Mask {
+	if len(masks) == 0 {
+		return masks
+	}
+
+	fitted := make(map[string]Mask, len(masks))
+	for _, c := range columns {
+		mask, ok := masks[c.Name]
+		if !ok {
+			continue
+		}
+		if match := characterType.FindStringSubmatch(c.Type); match != nil {
+			mask.limit, _ = strconv.Atoi(match[1])
+		}
+		fitted[c.Name] = mask
+	}
+	return fitted
+}
+
+func maskRow(key []byte, masks map[string]Mask, row map[string]any) {
+	for column, mask := range masks {
+		if value, ok := row[column]; ok {
+			row[column] = mask.apply(key, value)
+		}
+	}
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// hashValue keeps the type of the value so the masked row can be restored in the same columns.
+func hashValue(key []byte, value any) any {
+	switch v := value.(type) {
+	case int16:
+		return int16(permuteSigned(key, uint64(uint16(v)), 16))
+	case int32:
+		return int32(permuteSigned(key, uint64(uint32(v)), 32))
+	case int64:
+		return int64(permuteSigned(key, uint64(v), 64))
+	case int:
+		return int(permuteSigned(key, uint64(v), 64))
+	case []byte:
+		return hmacSum(key, v)
+	case string:
+		if uuidPattern.MatchString(v) {
+			sum := hmacSum(key, []byte(strings.ToLower(v)))
+			sum[6] = sum[6]&0x0f | 0x40 // version 4 layout
+			sum[8] = sum[8]&0x3f | 0x80
+			h := hex.EncodeToString(sum[:16])
+			return h[:8] + "-" + h[8:12] + "-" + h[12:16] + "-" + h[16:20] + "-" + h[20:]
+		}
+		return hashText(key, v)
+	default:
+		return hashText(key, textValue(v))
+	}
+}
+
+// hashText is a 16 characters keyed hash of the text.
+func hashText(key []byte, value string) string {
+	return hex.EncodeToString(hmacSum(key, []byte(value)))[:16]
+}
+
+func hmacSum(key, value []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write(value)
+	return mac.Sum(nil)
+}
+
+// permute is a keyed Feistel permutation over integers of the given bit size,
+// distinct ids stay distinct so masked primary and foreign keys keep matching without collisions.
+func permute(key []byte, value uint64, bits uint) uint64 {
+	half := bits / 2
+	mask := uint64(1)<<half - 1
+	left, right := value>>half&mask, value&mask
+
+	buf := make([]byte, 9)
+	for round := byte(0); round < 4; round++ {
+		buf[0] = round
+		binary.BigEndian.PutUint64(buf[1:], right)
+		f := binary.BigEndian.Uint64(hmacSum(key, buf)) & mask
+		left, right = right, left^f
+	}
+
+	return left<<half | right
+}
+
+// permuteSigned walks the permutation cycle until the sign bit matches the value, so positive ids stay positive.
+func permuteSigned(key []byte, value uint64, bits uint) uint64 {
+	sign := uint64(1) << (bits - 1)
+	result := permute(key, value, bits)
+	for result&sign != value&sign {
+		result = permute(key, result, bits)
+	}
+	return result
+}
+
+// maskFaker is a faker seeded by the keyed hash of the value, the same value always fakes the same.
+func maskFaker(key []byte, value string) *faker.Faker {
+	return faker.New(binary.BigEndian.Uint64(hmacSum(key, []byte(value))))
+}
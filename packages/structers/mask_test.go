@@ -0,0 +1,140 @@
+package structers
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestPermute(t *testing.T) {
+	tests := []struct {
+		name string
+		key  []byte
+		bits uint
+	}{
+		{name: "16 bits", key: []byte("key"), bits: 16},
+		{name: "16 bits other key", key: []byte("other key"), bits: 16},
+		{name: "8 bits", key: []byte("key"), bits: 8},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size := uint64(1) << tt.bits
+			seen := make(map[uint64]uint64, size)
+			moved := 0
+
+			for value := uint64(0); value < size; value++ {
+				got := permute(tt.key, value, tt.bits)
+				if got >= size {
+					t.Fatalf("permute(%d) = %d, out of the %d bits range", value, got, tt.bits)
+				}
+				if prev, ok := seen[got]; ok {
+					t.Fatalf("permute(%d) = permute(%d) = %d, not a permutation", value, prev, got)
+				}
+				seen[got] = value
+
+				if got != permute(tt.key, value, tt.bits) {
+					t.Fatalf("permute(%d) is not deterministic", value)
+				}
+				if got != value {
+					moved++
+				}
+			}
+
+			if moved < int(size)/2 {
+				t.Errorf("only %d of %d values moved", moved, size)
+			}
+		})
+	}
+}
+
+func TestPermuteSigned(t *testing.T) {
+	key := []byte("key")
+	seen := make(map[int16]int16)
+
+	for value := -1 << 15; value < 1<<15; value++ {
+		v := int16(value)
+		got := hashValue(key, v).(int16)
+		if (got < 0) != (v < 0) {
+			t.Fatalf("hashValue(%d) = %d, the sign changed", v, got)
+		}
+		if prev, ok := seen[got]; ok {
+			t.Fatalf("hashValue(%d) = hashValue(%d) = %d, not a permutation", v, prev, got)
+		}
+		seen[got] = v
+	}
+
+	if a, b := hashValue([]byte("a"), int64(42)), hashValue([]byte("b"), int64(42)); a == b {
+		t.Errorf("distinct keys masked 42 to the same %v", a)
+	}
+}
+
+func TestMaskApply(t *testing.T) {
+	key := bytes.Repeat([]byte{1}, 32)
+
+	tests := []struct {
+		name    string
+		mask    Mask
+		columns []ManifestColumn
+		value   any
+		check   func(any) bool
+	}{
+		{
+			name:  "hash is 16 characters",
+			mask:  Mask{Type: MaskHash},
+			value: "john",
+			check: func(v any) bool { return len(v.(string)) == 16 },
+		},
+		{
+			name:    "hash fits a varchar(8)",
+			mask:    Mask{Type: MaskHash},
+			columns: []ManifestColumn{{Name: "column", Type: "character varying(8)"}},
+			value:   "john",
+			check:   func(v any) bool { return len(v.(string)) == 8 },
+		},
+		{
+			name:    "email fits a char(10)",
+			mask:    Mask{Type: MaskEmail},
+			columns: []ManifestColumn{{Name: "column", Type: "character(10)"}},
+			value:   "john@example.com",
+			check:   func(v any) bool { return len(v.(string)) == 10 },
+		},
+		{
+			name:    "text is not limited",
+			mask:    Mask{Type: MaskHash},
+			columns: []ManifestColumn{{Name: "column", Type: "text"}},
+			value:   "john",
+			check:   func(v any) bool { return len(v.(string)) == 16 },
+		},
+		{
+			name:  "uuid stays a uuid",
+			mask:  Mask{Type: MaskHash},
+			value: "0f8fad5b-d9cb-469f-a165-70867728950e",
+			check: func(v any) bool {
+				return uuidPattern.MatchString(v.(string)) && v != "0f8fad5b-d9cb-469f-a165-70867728950e"
+			},
+		},
+		{
+			name:  "null stays null",
+			mask:  Mask{Type: MaskHash},
+			value: nil,
+			check: func(v any) bool { return v == nil },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			masks := map[string]Mask{"column": tt.mask}
+			if tt.columns != nil {
+				masks = fitMasks(masks, tt.columns)
+			}
+
+			got := masks["column"].apply(key, tt.value)
+			if !tt.check(got) {
+				t.Errorf("masked %v to %v", tt.value, got)
+			}
+			if again := masks["column"].apply(key, tt.value); again != got {
+				t.Errorf("masked %v to %v then %v", tt.value, got, again)
+			}
+		})
+	}
+}
@@ -0,0 +1,346 @@
+package structers
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Migration is a versioned SQL migration loaded from the migrations directory,
+// stored as `<version>_<name>.up.sql` and `<version>_<name>.down.sql`.
+type Migration struct {
+	Version string
+	Name    string
+	Up      string
+	Down    string
+}
+
+// SchemaMigration tracks the versioned migrations applied to the database.
+type SchemaMigration struct {
+	Version   string    `gorm:"primaryKey;size:14"`
+	Name      string    `gorm:"not null"`
+	Batch     int       `gorm:"not null;default:1;index"`
+	AppliedAt time.Time `gorm:"not null;default:now()"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationStatus describes a versioned migration and whether it has been applied.
+type MigrationStatus struct {
+	Version   string
+	Name      string
+	Applied   bool
+	Missing   bool // applied but the migration files no longer exist
+	Batch     int
+	AppliedAt *time.Time
+}
+
+// LoadMigrations reads every migration in dir ordered by version,
+// a missing directory is treated as no migrations.
+func LoadMigrations(dir string) ([]Migration, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read migrations directory: %v", err)
+	}
+
+	migrations := make(map[string]*Migration)
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+
+		var direction string
+		base := entry.Name()
+		switch {
+		case strings.HasSuffix(base, ".up.sql"):
+			direction = "up"
+			base = strings.TrimSuffix(base, ".up.sql")
+		case strings.HasSuffix(base, ".down.sql"):
+			direction = "down"
+			base = strings.TrimSuffix(base, ".down.sql")
+		default:
+			continue
+		}
+
+		// versions are the timestamps written by make:migration
+		version, name, ok := strings.Cut(base, "_")
+		if !ok || version == "" || strings.Trim(version, "0123456789") != "" {
+			return nil, fmt.Errorf("invalid migration filename %s", entry.Name())
+		}
+
+		content, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %v", entry.Name(), err)
+		}
+
+		m, exists := migrations[version]
+		if !exists {
+			m = &Migration{Version: version, Name: name}
+			migrations[version] = m
+		} else if m.Name != name {
+			return nil, fmt.Errorf("duplicate migration version %s (%s, %s)", version, m.Name, name)
+		}
+
+		if direction == "up" {
+			m.Up = string(content)
+		} else {
+			m.Down = string(content)
+		}
+	}
+
+	result := make([]Migration, 0, len(migrations))
+	for _, m := range migrations {
+		result = append(result, *m)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+
+	return result, nil
+}
+
+// WriteMigration writes a new `<version>_<name>` pair of migration files to dir and returns the version.
+func WriteMigration(dir, name string, up, down []string) (string, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create migrations directory: %v", err)
+	}
+
+	version := time.Now().Format("20060102150405")
+	files := map[string][]string{"up": up, "down": down}
+
+	for direction, statements := range files {
+		content := fmt.Sprintf("-- migration: %s_%s (%s)\n%s\n", version, name, direction, strings.Join(statements, "\n"))
+		path := filepath.Join(dir, fmt.Sprintf("%s_%s.%s.sql", version, name, direction))
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			return "", fmt.Errorf("failed to write migration %s: %v", path, err)
+		}
+	}
+
+	return version, nil
+}
+
+func (r *DatabaseRegistry) MigrationStatus(db *gorm.DB) ([]MigrationStatus, error) {
+	migrations, err := LoadMigrations(r.Migrations)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := appliedMigrations(db)
+	if err != nil {
+		return nil, err
+	}
+
+	applied := make(map[string]SchemaMigration, len(records))
+	for _, rec := range records {
+		applied[rec.Version] = rec
+	}
+
+	result := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.Version, Name: m.Name}
+		if rec, ok := applied[m.Version]; ok {
+			status.Applied = true
+			status.Batch = rec.Batch
+			status.AppliedAt = &rec.AppliedAt
+			delete(applied, m.Version)
+		}
+		result = append(result, status)
+	}
+
+	for _, rec := range applied {
+		result = append(result, MigrationStatus{
+			Version:   rec.Version,
+			Name:      rec.Name,
+			Applied:   true,
+			Missing:   true,
+			Batch:     rec.Batch,
+			AppliedAt: &rec.AppliedAt,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Version < result[j].Version
+	})
+
+	return result, nil
+}
+
+// Rollback reverts the last batch of migrations, or the last `step` migrations when step > 0.
+func (r *DatabaseRegistry) Rollback(db *gorm.DB, step int) error {
+	slog.Info("Starting migration rollback...")
+
+	count, err := r.revert(db, func(records []SchemaMigration) []SchemaMigration {
+		return rollbackSelection(records, step)
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.Info("✅ Migration rollback successfully", slog.Int("count", count))
+	return nil
+}
+
+// Reset reverts every applied migration.
+func (r *DatabaseRegistry) Reset(db *gorm.DB) error {
+	slog.Info("Starting migration reset...")
+
+	count, err := r.revert(db, func(records []SchemaMigration) []SchemaMigration {
+		return records
+	})
+	if err != nil {
+		return err
+	}
+
+	slog.Info("✅ Migration reset successfully", slog.Int("count", count))
+	return nil
+}
+
+// revert rolls back the migrations selected by pick from the applied ones (newest first).
+func (r *DatabaseRegistry) revert(db *gorm.DB, pick func([]SchemaMigration) []SchemaMigration) (int, error) {
+	migrations, err := LoadMigrations(r.Migrations)
+	if err != nil {
+		return 0, err
+	}
+
+	files := make(map[string]Migration, len(migrations))
+	for _, m := range migrations {
+		files[m.Version] = m
+	}
+
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := acquireMigrationLock(tx, r.LockTimeout); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	records, err := appliedMigrations(tx)
+	if err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	targets := pick(records)
+	if len(targets) == 0 {
+		slog.Info("Nothing to rollback")
+		return 0, tx.Rollback().Error
+	}
+
+	if err := revertMigrations(tx, files, targets); err != nil {
+		tx.Rollback()
+		return 0, err
+	}
+
+	return len(targets), tx.Commit().Error
+}
+
+// helper
+
+// appliedMigrations returns the applied migrations, newest first.
+func appliedMigrations(db *gorm.DB) ([]SchemaMigration, error) {
+	var records []SchemaMigration
+	if !db.Migrator().HasTable(&SchemaMigration{}) {
+		return records, nil
+	}
+
+	err := db.Order("batch DESC").Order("version DESC").Find(&records).Error
+	return records, err
+}
+
+// rollbackSelection picks from the applied migrations, newest first, the last batch or the last step ones.
+func rollbackSelection(records []SchemaMigration, step int) []SchemaMigration {
+	if step > 0 {
+		return records[:min(step, len(records))]
+	}
+
+	var targets []SchemaMigration
+	for _, rec := range records {
+		if rec.Batch != records[0].Batch {
+			break
+		}
+		targets = append(targets, rec)
+	}
+	return targets
+}
+
+func revertMigrations(tx *gorm.DB, files map[string]Migration, records []SchemaMigration) error {
+	for _, rec := range records {
+		m, ok := files[rec.Version]
+		if !ok {
+			return fmt.Errorf("migration file for %s_%s not found", rec.Version, rec.Name)
+		}
+
+		if strings.TrimSpace(m.Down) != "" {
+			if err := tx.Exec(m.Down).Error; err != nil {
+				return fmt.Errorf("failed to rollback migration %s_%s: %v", m.Version, m.Name, err)
+			}
+		}
+
+		if err := tx.Delete(&SchemaMigration{}, "version = ?", rec.Version).Error; err != nil {
+			return err
+		}
+
+		slog.Info("Rolled back", slog.String("migration", m.Version+"_"+m.Name))
+	}
+	return nil
+}
+
+func runMigrations(tx *gorm.DB, dir string) error {
+	migrations, err := LoadMigrations(dir)
+	if err != nil {
+		return err
+	}
+
+	// read before the table is created, a pretend run never creates it
+	applied, err := appliedMigrations(tx)
+	if err != nil {
+		return err
+	}
+
+	if err := tx.AutoMigrate(&SchemaMigration{}); err != nil {
+		return err
+	}
+
+	batch := 1
+	done := make(map[string]bool, len(applied))
+	for _, rec := range applied {
+		done[rec.Version] = true
+		batch = max(batch, rec.Batch+1)
+	}
+
+	for _, m := range migrations {
+		if done[m.Version] {
+			continue
+		}
+
+		if strings.TrimSpace(m.Up) != "" {
+			if err := tx.Exec(m.Up).Error; err != nil {
+				return fmt.Errorf("failed to apply migration %s_%s: %v", m.Version, m.Name, err)
+			}
+		}
+
+		record := SchemaMigration{Version: m.Version, Name: m.Name, Batch: batch, AppliedAt: time.Now()}
+		if err := tx.Create(&record).Error; err != nil {
+			return err
+		}
+
+		slog.Info("Migrated", slog.String("migration", m.Version+"_"+m.Name))
+	}
+
+	return nil
+}
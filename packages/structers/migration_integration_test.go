@@ -0,0 +1,89 @@
+package structers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMigrateBatchesAndRollback(t *testing.T) {
+	db := testDB(t)
+	dir := t.TempDir()
+	registry := &DatabaseRegistry{Migrations: dir}
+
+	write := func(version, table string) {
+		t.Helper()
+		for direction, sql := range map[string]string{
+			"up":   "CREATE TABLE " + table + " (id int)",
+			"down": "DROP TABLE " + table,
+		} {
+			name := filepath.Join(dir, version+"_create_"+table+"."+direction+".sql")
+			if err := os.WriteFile(name, []byte(sql), 0644); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	batches := func() map[string]int {
+		t.Helper()
+		statuses, err := registry.MigrationStatus(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		result := make(map[string]int)
+		for _, s := range statuses {
+			if s.Applied {
+				result[s.Version] = s.Batch
+			}
+		}
+		return result
+	}
+
+	write("20250101000000", "users")
+	if err := registry.Migrate(db, false); err != nil {
+		t.Fatal(err)
+	}
+	write("20250102000000", "orders")
+	write("20250103000000", "items")
+	if err := registry.Migrate(db, false); err != nil {
+		t.Fatal(err)
+	}
+
+	want := map[string]int{"20250101000000": 1, "20250102000000": 2, "20250103000000": 2}
+	if got := batches(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("batches = %v, want %v", got, want)
+	}
+
+	// the last batch
+	if err := registry.Rollback(db, 0); err != nil {
+		t.Fatal(err)
+	}
+	if got := batches(); !reflect.DeepEqual(got, map[string]int{"20250101000000": 1}) {
+		t.Fatalf("batches after rollback = %v", got)
+	}
+	if db.Migrator().HasTable("orders") || db.Migrator().HasTable("items") {
+		t.Error("rollback kept the tables of the last batch")
+	}
+
+	// migrated again as one batch, then one step back
+	if err := registry.Migrate(db, false); err != nil {
+		t.Fatal(err)
+	}
+	if err := registry.Rollback(db, 1); err != nil {
+		t.Fatal(err)
+	}
+	want = map[string]int{"20250101000000": 1, "20250102000000": 2}
+	if got := batches(); !reflect.DeepEqual(got, want) {
+		t.Fatalf("batches after one step = %v, want %v", got, want)
+	}
+
+	if err := registry.Reset(db); err != nil {
+		t.Fatal(err)
+	}
+	if got := batches(); len(got) != 0 {
+		t.Errorf("batches after reset = %v", got)
+	}
+	if db.Migrator().HasTable("users") {
+		t.Error("reset kept the users table")
+	}
+}
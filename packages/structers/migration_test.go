@@ -0,0 +1,152 @@
+package structers
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	tests := []struct {
+		name    string
+		files   map[string]string
+		want    []Migration
+		wantErr string
+	}{
+		{
+			name: "ordered by version",
+			files: map[string]string{
+				"20250102000000_add_index.up.sql":      "CREATE INDEX",
+				"20250101000000_create_users.up.sql":   "CREATE TABLE",
+				"20250101000000_create_users.down.sql": "DROP TABLE",
+			},
+			want: []Migration{
+				{Version: "20250101000000", Name: "create_users", Up: "CREATE TABLE", Down: "DROP TABLE"},
+				{Version: "20250102000000", Name: "add_index", Up: "CREATE INDEX"},
+			},
+		},
+		{
+			name:  "name keeps its underscores",
+			files: map[string]string{"20250101000000_rename_user_name.up.sql": "ALTER TABLE"},
+			want:  []Migration{{Version: "20250101000000", Name: "rename_user_name", Up: "ALTER TABLE"}},
+		},
+		{
+			name: "other files are ignored",
+			files: map[string]string{
+				"README.md":                          "notes",
+				"20250101000000_create_users.sql":    "no direction",
+				"20250101000000_create_users.up.sql": "CREATE TABLE",
+			},
+			want: []Migration{{Version: "20250101000000", Name: "create_users", Up: "CREATE TABLE"}},
+		},
+		{
+			name:    "missing version",
+			files:   map[string]string{"create_users.up.sql": "CREATE TABLE"},
+			wantErr: "invalid migration filename create_users.up.sql",
+		},
+		{
+			name:    "empty version",
+			files:   map[string]string{"_create_users.up.sql": "CREATE TABLE"},
+			wantErr: "invalid migration filename",
+		},
+		{
+			name: "duplicate version",
+			files: map[string]string{
+				"20250101000000_create_users.up.sql":  "CREATE TABLE users",
+				"20250101000000_create_orders.up.sql": "CREATE TABLE orders",
+			},
+			wantErr: "duplicate migration version 20250101000000",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			for name, content := range tt.files {
+				if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			got, err := LoadMigrations(dir)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("migrations = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMigrationsMissingDirectory(t *testing.T) {
+	got, err := LoadMigrations(filepath.Join(t.TempDir(), "missing"))
+	if err != nil || len(got) != 0 {
+		t.Errorf("LoadMigrations = %v, %v, want no migrations", got, err)
+	}
+}
+
+func TestWriteMigration(t *testing.T) {
+	dir := t.TempDir()
+
+	version, err := WriteMigration(dir, "add_email", []string{"ALTER TABLE users ADD email text;"}, []string{"ALTER TABLE users DROP email;"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	migrations, err := LoadMigrations(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 1 || migrations[0].Version != version || migrations[0].Name != "add_email" {
+		t.Fatalf("migrations = %+v, want %s_add_email", migrations, version)
+	}
+	if !strings.Contains(migrations[0].Up, "ADD email") || !strings.Contains(migrations[0].Down, "DROP email") {
+		t.Errorf("migration = %+v, want the written statements", migrations[0])
+	}
+}
+
+func TestRollbackSelection(t *testing.T) {
+	// applied migrations as appliedMigrations returns them, newest first
+	records := []SchemaMigration{
+		{Version: "20250105000000", Batch: 3},
+		{Version: "20250104000000", Batch: 3},
+		{Version: "20250103000000", Batch: 2},
+		{Version: "20250102000000", Batch: 1},
+		{Version: "20250101000000", Batch: 1},
+	}
+
+	tests := []struct {
+		name    string
+		records []SchemaMigration
+		step    int
+		want    []string
+	}{
+		{name: "last batch", records: records, want: []string{"20250105000000", "20250104000000"}},
+		{name: "one step", records: records, step: 1, want: []string{"20250105000000"}},
+		{name: "steps across batches", records: records, step: 3, want: []string{"20250105000000", "20250104000000", "20250103000000"}},
+		{name: "more steps than applied", records: records, step: 10, want: []string{"20250105000000", "20250104000000", "20250103000000", "20250102000000", "20250101000000"}},
+		{name: "single batch", records: records[3:], want: []string{"20250102000000", "20250101000000"}},
+		{name: "nothing applied", records: nil, want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, rec := range rollbackSelection(tt.records, tt.step) {
+				got = append(got, rec.Version)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("rollback = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package structers
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// sqlRecorder is a gorm logger that keeps every statement which changes the database,
+// read queries (introspection, pluck, etc.), savepoints and failed statements are skipped.
+type sqlRecorder struct {
+	logger.Interface
+	statements *[]string
+}
+
+func newSQLRecorder(base logger.Interface) *sqlRecorder {
+	return &sqlRecorder{Interface: base, statements: &[]string{}}
+}
+
+func (l *sqlRecorder) LogMode(level logger.LogLevel) logger.Interface {
+	return &sqlRecorder{Interface: l.Interface.LogMode(level), statements: l.statements}
+}
+
+func (l *sqlRecorder) Statements() []string {
+	return *l.statements
+}
+
+func (l *sqlRecorder) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	sql, _ := fc()
+	if query := strings.TrimSpace(sql); err == nil && query != "" && isRecordable(query) {
+		*l.statements = append(*l.statements, strings.TrimSuffix(query, ";")+";")
+	}
+	l.Interface.Trace(ctx, begin, fc, err)
+}
+
+func isRecordable(sql string) bool {
+	switch sqlKeyword(sql) {
+	case "SELECT", "SHOW", "EXPLAIN", "SAVEPOINT", "RELEASE", "ROLLBACK":
+		return false
+	}
+	return true
+}
+
+func sqlKeyword(sql string) string {
+	keyword, _, _ := strings.Cut(strings.TrimLeft(sql, "( \t\n"), " ")
+	return strings.ToUpper(keyword)
+}
+
+// dryRunPool wraps the connection of a read only transaction so only reads reach the server,
+// statements changing the database succeed without being sent and return no rows.
+// Unlike a gorm DryRun session, introspection still sees the live schema.
+type dryRunPool struct {
+	gorm.ConnPool
+}
+
+// beginDryRun starts a read only transaction on a recording session whose writes are never executed.
+func beginDryRun(db *gorm.DB) (*gorm.DB, *sqlRecorder) {
+	recorder := newSQLRecorder(db.Logger)
+
+	tx := db.Session(&gorm.Session{Logger: recorder}).Begin(&sql.TxOptions{ReadOnly: true})
+	if tx.Error == nil {
+		tx.Statement.ConnPool = &dryRunPool{ConnPool: tx.Statement.ConnPool}
+	}
+	return tx, recorder
+}
+
+func (p *dryRunPool) ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error) {
+	switch sqlKeyword(query) {
+	case "SAVEPOINT", "RELEASE", "ROLLBACK":
+		return p.ConnPool.ExecContext(ctx, query, args...)
+	}
+	return driver.RowsAffected(0), nil
+}
+
+func (p *dryRunPool) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	if isRecordable(query) {
+		return p.ConnPool.QueryContext(ctx, "SELECT NULL WHERE false")
+	}
+	return p.ConnPool.QueryContext(ctx, query, args...)
+}
+
+func (p *dryRunPool) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	if isRecordable(query) {
+		return p.ConnPool.QueryRowContext(ctx, "SELECT NULL WHERE false")
+	}
+	return p.ConnPool.QueryRowContext(ctx, query, args...)
+}
+
+func (p *dryRunPool) Commit() error {
+	return p.ConnPool.(gorm.TxCommitter).Commit()
+}
+
+func (p *dryRunPool) Rollback() error {
+	return p.ConnPool.(gorm.TxCommitter).Rollback()
+}
+
+// emptySchema hides the tables and types of the current schema from the following reads,
+// e.g. after recording `migrate --fresh` drops that were never executed.
+func (p *dryRunPool) emptySchema(ctx context.Context) error {
+	_, err := p.ConnPool.ExecContext(ctx, "SET LOCAL search_path = ''")
+	return err
+}
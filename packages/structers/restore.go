@@ -0,0 +1,606 @@
+package structers
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strings"
+	"time"
+	"webservices/packages/common"
+
+	"gorm.io/gorm"
+)
+
+// RestoreOptions configures DatabaseRegistry.Restore.
+type RestoreOptions struct {
+	Input     string
+	Truncate  bool // empty the tables before loading
+	Upsert    bool // update rows that already exist by primary key
+	BatchSize int
+	Key       []byte // decrypts encrypted archives
+
+	// Confirm is asked before truncating with the restored tables and the tables referencing them,
+	// nothing is restored when it returns false
+	Confirm func(truncated []string) bool
+}
+
+// Restore loads every table listed in the manifest of the newest run found in the input directory,
+// or the newest file of each registered table for backups without manifest.
+// Parents are loaded before children inside a single transaction.
+// The input may also be a backup archive, or a directory holding archives only, which is
+// decrypted and extracted to a temporary directory first.
+// An incremental backup is replayed on top of the full backup and incrementals it is based on.
+func (r *DatabaseRegistry) Restore(db *gorm.DB, opts RestoreOptions) error {
+	slog.Info("Starting database restore...")
+
+	if opts.BatchSize <= 0 {
+		opts.BatchSize = DefaultBackupBatchSize
+	}
+
+	chain, err := r.backupChain(opts.Input, opts.Key)
+	if err != nil {
+		return err
+	}
+	defer closeChain(chain)
+
+	// manifests also list tables discovered outside of the registry
+	var tables []string
+	for _, run := range chain {
+		for table := range run.files {
+			if !slices.Contains(tables, table) {
+				tables = append(tables, table)
+			}
+		}
+	}
+	slices.Sort(tables)
+
+	if len(tables) == 0 {
+		return fmt.Errorf("no backup files found in %s", opts.Input)
+	}
+
+	tables, err = foreignKeyOrder(db, tables)
+	if err != nil {
+		return err
+	}
+
+	if opts.Truncate && opts.Confirm != nil {
+		cascaded, err := referencingTables(db, tables)
+		if err != nil {
+			return err
+		}
+		if !opts.Confirm(append(slices.Clone(tables), cascaded...)) {
+			return nil
+		}
+	}
+
+	tx := db.Begin()
+	defer func() {
+		if r := recover(); r != nil {
+			tx.Rollback()
+		}
+	}()
+
+	if err := r.restoreTables(tx, tables, chain, opts); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit().Error; err != nil {
+		return err
+	}
+
+	slog.Info("✅ Database restore completed successfully")
+	return nil
+}
+
+// LatestBackups finds the newest `backup_<table>_<timestamp>.<ext>` file of each registered table in dir,
+// only formats with a reader are considered.
+func (r *DatabaseRegistry) LatestBackups(dir string) (map[string]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %v", err)
+	}
+
+	latest := make(map[string]string)
+	stamps := make(map[string]string)
+
+	for _, entry := range entries {
+		table, timestamp, ext, ok := parseBackupName(entry.Name())
+		if entry.IsDir() || !ok || !slices.Contains(r.Tables, table) {
+			continue
+		}
+
+		if format, ok := r.formatByExtension(ext); !ok || format.Reader == nil {
+			continue
+		}
+
+		if timestamp > stamps[table] {
+			stamps[table] = timestamp
+			latest[table] = filepath.Join(dir, entry.Name())
+		}
+	}
+
+	return latest, nil
+}
+
+// helper
+
+// backupInput resolves the directory of a backup, extracting the archive given as input
+// or the newest archive of a directory without a manifest or loose backup files.
+func (r *DatabaseRegistry) backupInput(input string, key []byte) (string, func(), error) {
+	noop := func() {}
+
+	info, err := os.Stat(input)
+	if err != nil {
+		return "", noop, fmt.Errorf("failed to read backup input: %v", err)
+	}
+
+	archive := input
+	if info.IsDir() {
+		if path, err := manifestPath(input); err != nil || path != "" {
+			return input, noop, err
+		}
+
+		files, err := r.LatestBackups(input)
+		if err != nil || len(files) > 0 {
+			return input, noop, err
+		}
+		if archive, err = latestArchive(input); err != nil || archive == "" {
+			return input, noop, err
+		}
+	} else if !isArchive(info.Name()) {
+		return "", noop, fmt.Errorf("%s is not a backup archive", input)
+	}
+
+	dir, cleanup, err := privateTempDir("restore_")
+	if err != nil {
+		return "", noop, err
+	}
+
+	slog.Info("Extracting backup archive", slog.String("path", archive))
+	if err := extractArchive(archive, key, dir); err != nil {
+		cleanup()
+		return "", noop, err
+	}
+
+	return dir, cleanup, nil
+}
+
+// backupName matches `backup_<table>_<timestamp>.<ext>`, the table may contain underscores and dots (public.users).
+var backupName = regexp.MustCompile(`^backup_(.+)_([0-9]+)\.([^.]+(?:\.[^.]+)*)$`)
+
+// parseBackupName splits `backup_<table>_<timestamp>.<ext>`, on the last timestamp followed by the extension.
+func parseBackupName(name string) (table, timestamp, ext string, ok bool) {
+	match := backupName.FindStringSubmatch(name)
+	if match == nil {
+		return "", "", "", false
+	}
+	return match[1], match[2], match[3], true
+}
+
+func (r *DatabaseRegistry) restoreTables(tx *gorm.DB, tables []string, chain []*backupRun, opts RestoreOptions) error {
+	// constraints declared deferrable may reference rows loaded later (cycles)
+	if err := tx.Exec("SET CONSTRAINTS ALL DEFERRED").Error; err != nil {
+		return err
+	}
+
+	if opts.Truncate {
+		// tables referencing the restored ones are emptied as well, their rows would point nowhere
+		cascaded, err := referencingTables(tx, tables)
+		if err != nil {
+			return err
+		}
+		if len(cascaded) > 0 {
+			slog.Warn("Truncating tables referencing the restored tables", slog.Any("tables", cascaded))
+		}
+
+		quoted := make([]string, len(tables))
+		for i, t := range tables {
+			quoted[i] = quoteIdent(t)
+		}
+		if err := tx.Exec(fmt.Sprintf("TRUNCATE %s CASCADE", strings.Join(quoted, ", "))).Error; err != nil {
+			return fmt.Errorf("failed to truncate tables: %v", err)
+		}
+	}
+
+	for i, run := range chain {
+		for _, table := range tables {
+			file, ok := run.files[table]
+			if !ok {
+				continue
+			}
+			start := time.Now()
+
+			// on top of the full backup, changed rows are upserted and tables exported in full replaced
+			tableOpts := opts
+			if i > 0 {
+				tableOpts.Upsert = run.incremental(table)
+				if !tableOpts.Upsert {
+					if err := tx.Exec(fmt.Sprintf("DELETE FROM %s", quoteIdent(table))).Error; err != nil {
+						return fmt.Errorf("failed to replace table %s: %v", table, err)
+					}
+				}
+			}
+
+			_, _, ext, _ := parseBackupName(filepath.Base(file))
+			format, _ := r.formatByExtension(ext)
+
+			count, err := restoreTable(tx, table, file, format.Reader, tableOpts)
+			if err != nil {
+				return err
+			}
+
+			attrs := []any{
+				slog.String("table", table),
+				slog.Int64("rows", count),
+				slog.Duration("duration", time.Since(start).Round(time.Millisecond)),
+			}
+			if run.manifest != nil {
+				attrs = append(attrs, slog.String("run", run.manifest.Run()))
+			}
+			slog.Info("Restored table", attrs...)
+		}
+	}
+
+	for _, table := range tables {
+		if err := resetSequences(tx, table); err != nil {
+			return fmt.Errorf("failed to reset sequences of table %s: %v", table, err)
+		}
+	}
+
+	return nil
+}
+
+func restoreTable(tx *gorm.DB, table, filePath string, read BackupReader, opts RestoreOptions) (int64, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return 0, fmt.Errorf("failed to open file %s: %v", filePath, err)
+	}
+	defer file.Close()
+
+	columns, err := tableColumns(tx, table)
+	if err != nil {
+		return 0, err
+	}
+
+	binary, err := byteaColumns(tx, table)
+	if err != nil {
+		return 0, err
+	}
+
+	var keys []string
+	if opts.Upsert {
+		if keys, err = primaryKeys(tx, table); err != nil {
+			return 0, err
+		}
+		if len(keys) == 0 {
+			return 0, fmt.Errorf("table %s has no primary key to upsert on", table)
+		}
+	}
+
+	var count int64
+	batch := make([]map[string]any, 0, opts.BatchSize)
+
+	flush := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		if err := insertRows(tx, table, batch, keys); err != nil {
+			return fmt.Errorf("failed to restore table %s: %v", table, err)
+		}
+		count += int64(len(batch))
+		batch = batch[:0]
+		return nil
+	}
+
+	err = read(file, func(record map[string]any) error {
+		row := make(map[string]any, len(record))
+		for key, value := range record {
+			column, ok := columns[key]
+			if !ok {
+				continue
+			}
+			if binary[column] {
+				if value, err = byteaValue(value); err != nil {
+					return fmt.Errorf("column %s: %v", column, err)
+				}
+			}
+			row[column] = value
+		}
+
+		batch = append(batch, row)
+		if len(batch) >= opts.BatchSize {
+			return flush()
+		}
+		return nil
+	})
+	if err != nil {
+		return count, fmt.Errorf("failed to read %s: %v", filePath, err)
+	}
+
+	return count, flush()
+}
+
+// readJSONArray decodes a JSON array one element at a time, numbers are kept as json.Number.
+func readJSONArray(r io.Reader, fn func(map[string]any) error) error {
+	dec := json.NewDecoder(r)
+	dec.UseNumber()
+
+	token, err := dec.Token()
+	if err != nil {
+		return err
+	}
+	if token == nil {
+		return nil // `null` written for empty tables
+	}
+	if delim, ok := token.(json.Delim); !ok || delim != '[' {
+		return fmt.Errorf("expected a JSON array")
+	}
+
+	for dec.More() {
+		var record map[string]any
+		if err := dec.Decode(&record); err != nil {
+			return err
+		}
+		if err := fn(record); err != nil {
+			return err
+		}
+	}
+
+	_, err = dec.Token()
+	return err
+}
+
+// insertRows lets postgres convert the JSON values to the column types through json_populate_recordset,
+// with keys the rows are upserted on them.
+func insertRows(tx *gorm.DB, table string, rows []map[string]any, keys []string) error {
+	var columns []string
+	for _, row := range rows {
+		for column := range row {
+			if !slices.Contains(columns, column) {
+				columns = append(columns, column)
+			}
+		}
+	}
+	slices.Sort(columns)
+
+	data, err := json.Marshal(rows)
+	if err != nil {
+		return err
+	}
+
+	quoted := make([]string, len(columns))
+	for i, c := range columns {
+		quoted[i] = quoteIdent(c)
+	}
+	list := strings.Join(quoted, ", ")
+
+	query := fmt.Sprintf("INSERT INTO %s (%s) OVERRIDING SYSTEM VALUE SELECT %s FROM json_populate_recordset(NULL::%s, ?::json)",
+		quoteIdent(table), list, list, quoteIdent(table))
+
+	if len(keys) > 0 {
+		query += onConflict(columns, keys, false)
+	}
+
+	return tx.Exec(query, string(data)).Error
+}
+
+// onConflict updates the other columns of rows conflicting on keys, or ignores them when there are none.
+// With returning they are updated anyway, so RETURNING reports every row.
+func onConflict(columns, keys []string, returning bool) string {
+	conflict := make([]string, len(keys))
+	for i, k := range keys {
+		conflict[i] = quoteIdent(k)
+	}
+
+	var updates []string
+	for _, c := range columns {
+		if !slices.Contains(keys, c) {
+			updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", quoteIdent(c), quoteIdent(c)))
+		}
+	}
+	if len(updates) == 0 && returning {
+		updates = append(updates, fmt.Sprintf("%s = EXCLUDED.%s", conflict[0], conflict[0]))
+	}
+
+	if len(updates) == 0 {
+		return fmt.Sprintf(" ON CONFLICT (%s) DO NOTHING", strings.Join(conflict, ", "))
+	}
+	return fmt.Sprintf(" ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(conflict, ", "), strings.Join(updates, ", "))
+}
+
+// tableColumns maps the column and camelCase backup keys of a table to its insertable column names.
+func tableColumns(tx *gorm.DB, table string) (map[string]string, error) {
+	var names []string
+	err := tx.Raw(`SELECT attname FROM pg_attribute
+		WHERE attrelid = to_regclass(?) AND attnum > 0 AND NOT attisdropped AND attgenerated = ''`, quoteIdent(table)).Scan(&names).Error
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]string, len(names)*2)
+	for _, name := range names {
+		columns[name] = name
+		columns[common.ToCamelCase(name)] = name
+	}
+	return columns, nil
+}
+
+// byteaColumns returns the bytea columns of a table.
+func byteaColumns(tx *gorm.DB, table string) (map[string]bool, error) {
+	var names []string
+	err := tx.Raw(`SELECT attname FROM pg_attribute
+		WHERE attrelid = to_regclass(?) AND attnum > 0 AND NOT attisdropped AND atttypid = 'bytea'::regtype`, quoteIdent(table)).Scan(&names).Error
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make(map[string]bool, len(names))
+	for _, name := range names {
+		columns[name] = true
+	}
+	return columns, nil
+}
+
+// byteaValue converts the base64 JSON encoding of a bytea value to the hex form postgres reads,
+// values already in hex form (`\x...`, written by csv and sql) are kept.
+func byteaValue(value any) (any, error) {
+	s, ok := value.(string)
+	if !ok || strings.HasPrefix(s, `\x`) {
+		return value, nil
+	}
+
+	data, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base64 bytea value: %v", err)
+	}
+	return `\x` + hex.EncodeToString(data), nil
+}
+
+// referencingTables returns the tables outside tables whose foreign keys reference them, directly or not.
+func referencingTables(tx *gorm.DB, tables []string) ([]string, error) {
+	keys, err := foreignKeys(tx)
+	if err != nil {
+		return nil, err
+	}
+	names, err := tableOIDs(tx, tables)
+	if err != nil {
+		return nil, err
+	}
+
+	emptied := make(map[int64]bool, len(names))
+	for oid := range names {
+		emptied[oid] = true
+	}
+	var result []string
+	for changed := true; changed; {
+		changed = false
+		for _, key := range keys {
+			if emptied[key.ParentOID] && !emptied[key.ChildOID] {
+				emptied[key.ChildOID] = true
+				result = append(result, key.Child)
+				changed = true
+			}
+		}
+	}
+
+	slices.Sort(result)
+	return result, nil
+}
+
+// resetSequences moves the serial/identity sequences of a table past its highest value.
+func resetSequences(tx *gorm.DB, table string) error {
+	var columns []string
+	err := tx.Raw(`SELECT attname FROM pg_attribute
+		WHERE attrelid = to_regclass(?) AND attnum > 0 AND NOT attisdropped
+		AND pg_get_serial_sequence(?, attname) IS NOT NULL`, quoteIdent(table), quoteIdent(table)).Scan(&columns).Error
+	if err != nil {
+		return err
+	}
+
+	for _, column := range columns {
+		query := fmt.Sprintf("SELECT setval(pg_get_serial_sequence(?, ?), COALESCE(MAX(%s), 0) + 1, false) FROM %s",
+			quoteIdent(column), quoteIdent(table))
+		if err := tx.Exec(query, quoteIdent(table), column).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// foreignKeyOrder sorts tables so referenced tables come before the tables referencing them.
+func foreignKeyOrder(db *gorm.DB, tables []string) ([]string, error) {
+	parents, err := foreignKeyParents(db, tables)
+	if err != nil {
+		return nil, err
+	}
+
+	ordered := make([]string, 0, len(tables))
+	visited := make(map[string]int) // 1 visiting, 2 done
+
+	var visit func(string)
+	visit = func(table string) {
+		if visited[table] != 0 {
+			return // done, or a cycle which relies on deferred constraints
+		}
+		visited[table] = 1
+		for _, parent := range parents[table] {
+			visit(parent)
+		}
+		visited[table] = 2
+		ordered = append(ordered, table)
+	}
+
+	for _, table := range tables {
+		visit(table)
+	}
+
+	return ordered, nil
+}
+
+// foreignKeyParents returns the tables each table references by foreign key, limited to tables.
+func foreignKeyParents(db *gorm.DB, tables []string) (map[string][]string, error) {
+	keys, err := foreignKeys(db)
+	if err != nil {
+		return nil, err
+	}
+	names, err := tableOIDs(db, tables)
+	if err != nil {
+		return nil, err
+	}
+
+	parents := make(map[string][]string)
+	for _, key := range keys {
+		child, ok := names[key.ChildOID]
+		parent, found := names[key.ParentOID]
+		if ok && found && child != parent && !slices.Contains(parents[child], parent) {
+			parents[child] = append(parents[child], parent)
+		}
+	}
+	return parents, nil
+}
+
+// foreignKey links a referencing (child) table to the table it references (parent).
+type foreignKey struct {
+	Child     string // qualified with its schema outside of the current one, e.g. audit.logs
+	ChildOID  int64
+	ParentOID int64
+}
+
+// foreignKeys returns the foreign keys of every schema.
+func foreignKeys(db *gorm.DB) ([]foreignKey, error) {
+	var keys []foreignKey
+	err := db.Raw(`SELECT CASE WHEN c.relnamespace = current_schema()::regnamespace THEN c.relname
+			ELSE n.nspname || '.' || c.relname END AS child,
+			con.conrelid::bigint AS child_oid, con.confrelid::bigint AS parent_oid
+		FROM pg_constraint con
+		JOIN pg_class c ON c.oid = con.conrelid
+		JOIN pg_namespace n ON n.oid = c.relnamespace
+		WHERE con.contype = 'f'`).Scan(&keys).Error
+	return keys, err
+}
+
+// tableOIDs resolves tables, plain or qualified with their schema (public.users), to their names by oid.
+// Tables that don't exist are left out.
+func tableOIDs(db *gorm.DB, tables []string) (map[int64]string, error) {
+	names := make(map[int64]string, len(tables))
+	for _, table := range tables {
+		var oid *int64
+		if err := db.Raw("SELECT to_regclass(?)::oid::bigint", quoteIdent(table)).Scan(&oid).Error; err != nil {
+			return nil, err
+		}
+		if oid != nil {
+			if _, ok := names[*oid]; !ok {
+				names[*oid] = table
+			}
+		}
+	}
+	return names, nil
+}
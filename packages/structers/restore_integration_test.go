@@ -0,0 +1,96 @@
+package structers
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestRestoreTableBytea(t *testing.T) {
+	db := testDB(t)
+
+	if err := db.Exec("CREATE TABLE files (id int PRIMARY KEY, data bytea)").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	file := filepath.Join(t.TempDir(), "backup_files_202501010000.json")
+	if err := os.WriteFile(file, []byte(`[{"id": 1, "data": "AN6tvu8="}, {"id": 2, "data": null}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	count, err := restoreTable(db, "files", file, readJSONArray, RestoreOptions{BatchSize: 10})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("restored %d rows, want 2", count)
+	}
+
+	var data []byte
+	if err := db.Raw("SELECT data FROM files WHERE id = 1").Scan(&data).Error; err != nil {
+		t.Fatal(err)
+	}
+	if want := []byte{0x00, 0xde, 0xad, 0xbe, 0xef}; !bytes.Equal(data, want) {
+		t.Errorf("data = %x, want %x", data, want)
+	}
+}
+
+func TestReferencingTables(t *testing.T) {
+	db := testDB(t)
+
+	err := db.Exec(`CREATE TABLE users (id int PRIMARY KEY);
+		CREATE TABLE orders (id int PRIMARY KEY, user_id int REFERENCES users);
+		CREATE TABLE items (id int PRIMARY KEY, order_id int REFERENCES orders);
+		CREATE TABLE tags (id int PRIMARY KEY)`).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tables, err := referencingTables(db, []string{"users"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []string{"items", "orders"}; !slices.Equal(tables, want) {
+		t.Errorf("referencing tables = %v, want %v", tables, want)
+	}
+}
+
+func TestRestoreConfirmsTruncatedTables(t *testing.T) {
+	db := testDB(t)
+
+	err := db.Exec(`CREATE TABLE users (id int PRIMARY KEY);
+		CREATE TABLE orders (id int PRIMARY KEY, user_id int REFERENCES users);
+		INSERT INTO users VALUES (1);
+		INSERT INTO orders VALUES (1, 1)`).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "backup_users_202501010000.json"), []byte(`[{"id": 2}]`), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var asked []string
+	registry := &DatabaseRegistry{Tables: []string{"users"}}
+	err = registry.Restore(db, RestoreOptions{Input: dir, Truncate: true, Confirm: func(truncated []string) bool {
+		asked = truncated
+		return false
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if want := []string{"users", "orders"}; !slices.Equal(asked, want) {
+		t.Errorf("confirmed %v, want %v", asked, want)
+	}
+	var orders int64
+	if err := db.Table("orders").Count(&orders).Error; err != nil {
+		t.Fatal(err)
+	}
+	if orders != 1 {
+		t.Errorf("%d orders left after a declined truncate, want 1", orders)
+	}
+}
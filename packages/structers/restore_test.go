@@ -0,0 +1,72 @@
+package structers
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestByteaValue(t *testing.T) {
+	// backups encode bytea values like encoding/json encodes []byte
+	encoded, _ := json.Marshal([]byte{0x00, 0xde, 0xad, 0xbe, 0xef})
+
+	var base64 string
+	if err := json.Unmarshal(encoded, &base64); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		value   any
+		want    any
+		wantErr bool
+	}{
+		{name: "base64", value: base64, want: `\x00deadbeef`},
+		{name: "hex is kept", value: `\x00ff`, want: `\x00ff`},
+		{name: "empty", value: "", want: `\x`},
+		{name: "null", value: nil, want: nil},
+		{name: "invalid", value: "not base64!", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := byteaValue(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("byteaValue(%v) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseBackupName(t *testing.T) {
+	tests := []struct {
+		name      string
+		table     string
+		timestamp string
+		ext       string
+		ok        bool
+	}{
+		{name: "backup_users_202505180130.json", table: "users", timestamp: "202505180130", ext: "json", ok: true},
+		{name: "backup_user_roles_202505180130.ndjson", table: "user_roles", timestamp: "202505180130", ext: "ndjson", ok: true},
+		{name: "backup_public.users_202505180130.csv", table: "public.users", timestamp: "202505180130", ext: "csv", ok: true},
+		{name: "backup_audit.log_2024_202505180130.sql", table: "audit.log_2024", timestamp: "202505180130", ext: "sql", ok: true},
+		{name: "backup_users_202505180130.sql.gz", table: "users", timestamp: "202505180130", ext: "sql.gz", ok: true},
+		{name: "backup_202505180130.manifest.json"},
+		{name: "backup_202505180130.tar.gz.enc"},
+		{name: "backup_users_latest.json"},
+		{name: "users_202505180130.json"},
+		{name: "backup_users_202505180130"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table, timestamp, ext, ok := parseBackupName(tt.name)
+			if ok != tt.ok || table != tt.table || timestamp != tt.timestamp || ext != tt.ext {
+				t.Errorf("parseBackupName(%q) = %q, %q, %q, %v, want %q, %q, %q, %v",
+					tt.name, table, timestamp, ext, ok, tt.table, tt.timestamp, tt.ext, tt.ok)
+			}
+		})
+	}
+}
@@ -0,0 +1,257 @@
+package structers
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// RetentionPolicy decides which backup runs are kept, a run is kept when any tier keeps it.
+// Tiers keep the newest run of each of the last N days, weeks or months, a zero policy keeps everything.
+type RetentionPolicy struct {
+	Last    int
+	Daily   int
+	Weekly  int
+	Monthly int
+}
+
+// BackupRun is the set of files written by one db:backup run.
+type BackupRun struct {
+	ID        string // the timestamp in the file names, as referenced by the Parent of a manifest
+	Timestamp time.Time
+	Files     []string
+}
+
+func (p RetentionPolicy) Enabled() bool {
+	return p.Last > 0 || p.Daily > 0 || p.Weekly > 0 || p.Monthly > 0
+}
+
+// ListBackups finds the backup runs in root and its dated subdirectories, newest first.
+func ListBackups(root string) ([]BackupRun, error) {
+	dirs := []string{root}
+
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read backup directory: %v", err)
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && !strings.HasPrefix(entry.Name(), ".") {
+			dirs = append(dirs, filepath.Join(root, entry.Name()))
+		}
+	}
+
+	runs := make(map[string]*BackupRun)
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(dir)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read backup directory: %v", err)
+		}
+
+		for _, entry := range entries {
+			if entry.IsDir() {
+				continue
+			}
+
+			stamp, ok := backupTimestamp(entry.Name())
+			if !ok {
+				continue
+			}
+
+			// runs are keyed by directory, the same id may exist in two folders
+			key := filepath.Join(dir, stamp)
+			if runs[key] == nil {
+				t, ok := parseRunID(stamp)
+				if !ok {
+					continue
+				}
+				runs[key] = &BackupRun{ID: stamp, Timestamp: t}
+			}
+			runs[key].Files = append(runs[key].Files, filepath.Join(dir, entry.Name()))
+		}
+	}
+
+	result := make([]BackupRun, 0, len(runs))
+	for _, run := range runs {
+		result = append(result, *run)
+	}
+	slices.SortFunc(result, func(a, b BackupRun) int {
+		return b.Timestamp.Compare(a.Timestamp)
+	})
+
+	return result, nil
+}
+
+// PruneBackups deletes the backup runs in root the policy doesn't keep and returns them,
+// with dryRun nothing is deleted. Runs an incremental backup that is kept is based on are kept too,
+// the key reads the manifests of encrypted archives.
+func PruneBackups(root string, policy RetentionPolicy, key []byte, dryRun bool) ([]BackupRun, error) {
+	if !policy.Enabled() {
+		return nil, nil
+	}
+
+	runs, err := ListBackups(root)
+	if err != nil {
+		return nil, err
+	}
+
+	keep := policy.keep(runs)
+	if err := keepParents(runs, keep, key); err != nil {
+		return nil, err
+	}
+
+	var pruned []BackupRun
+	dirs := make(map[string]bool)
+
+	for i, run := range runs {
+		if keep[i] {
+			continue
+		}
+		pruned = append(pruned, run)
+
+		if dryRun {
+			continue
+		}
+		for _, file := range run.Files {
+			if err := os.Remove(file); err != nil {
+				return pruned, fmt.Errorf("failed to delete %s: %v", file, err)
+			}
+			dirs[filepath.Dir(file)] = true
+		}
+	}
+
+	for dir := range dirs {
+		if filepath.Clean(dir) != filepath.Clean(root) {
+			removeEmptyBackupDir(dir)
+		}
+	}
+
+	return pruned, nil
+}
+
+// helper
+
+// keep marks the runs, sorted newest first, kept by any of the tiers.
+func (p RetentionPolicy) keep(runs []BackupRun) map[int]bool {
+	keep := make(map[int]bool)
+
+	for i := 0; i < p.Last && i < len(runs); i++ {
+		keep[i] = true
+	}
+
+	tiers := []struct {
+		count  int
+		bucket func(time.Time) string
+	}{
+		{p.Daily, func(t time.Time) string { return t.Format("2006-01-02") }},
+		{p.Weekly, func(t time.Time) string {
+			year, week := t.ISOWeek()
+			return fmt.Sprintf("%d-W%02d", year, week)
+		}},
+		{p.Monthly, func(t time.Time) string { return t.Format("2006-01") }},
+	}
+
+	for _, tier := range tiers {
+		seen := make(map[string]bool)
+		for i, run := range runs {
+			if len(seen) >= tier.count {
+				break
+			}
+			bucket := tier.bucket(run.Timestamp)
+			if !seen[bucket] {
+				seen[bucket] = true
+				keep[i] = true
+			}
+		}
+	}
+
+	return keep
+}
+
+// keepParents marks the runs the kept incremental backups are based on.
+func keepParents(runs []BackupRun, keep map[int]bool, key []byte) error {
+	index := make(map[string]int, len(runs))
+	for i := len(runs) - 1; i >= 0; i-- {
+		index[runs[i].ID] = i
+	}
+
+	// runs are sorted newest first, parents always come later
+	for i, run := range runs {
+		if !keep[i] {
+			continue
+		}
+
+		manifest, err := runManifest(run, key)
+		if err != nil {
+			return fmt.Errorf("failed to read the manifest of backup %s: %v", run.ID, err)
+		}
+
+		if manifest != nil && manifest.Type == BackupIncremental {
+			if parent, ok := index[manifest.Parent]; ok {
+				keep[parent] = true
+			}
+		}
+	}
+
+	return nil
+}
+
+// backupTimestamp returns the timestamp of a table backup, run manifest or archive file name.
+func backupTimestamp(name string) (string, bool) {
+	if _, stamp, _, ok := parseBackupName(name); ok {
+		return stamp, true
+	}
+
+	if isArchive(name) || (strings.HasPrefix(name, "backup_") && strings.HasSuffix(name, manifestSuffix)) {
+		stamp, _, _ := strings.Cut(strings.TrimPrefix(name, "backup_"), ".")
+		return stamp, true
+	}
+
+	return "", false
+}
+
+// parseRunID reads the time of a run id, with seconds or, for older runs, without.
+func parseRunID(id string) (time.Time, bool) {
+	for _, layout := range []string{runIDLayout, legacyRunIDLayout} {
+		if len(id) != len(layout) {
+			continue
+		}
+		if t, err := time.ParseInLocation(layout, id, time.Local); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+// runExists reports whether dir already holds a file of the run with the given id.
+func runExists(dir, id string) (bool, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return false, fmt.Errorf("failed to read backup directory: %v", err)
+	}
+	for _, entry := range entries {
+		if stamp, ok := backupTimestamp(entry.Name()); ok && stamp == id {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// removeEmptyBackupDir removes a dated backup directory once only its manifest is left.
+func removeEmptyBackupDir(dir string) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return
+	}
+
+	for _, entry := range entries {
+		if entry.Name() != manifestName {
+			return
+		}
+	}
+
+	os.Remove(filepath.Join(dir, manifestName))
+	os.Remove(dir)
+}
@@ -0,0 +1,191 @@
+package structers
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"slices"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gorm.io/gorm"
+	"webservices/packages/factory"
+)
+
+// Seeder fills tables with generated data, register them in `Database.Seeders`.
+type Seeder struct {
+	Name    string
+	Depends []string // seeders run before this one, e.g. users before orders
+	Run     func(db *gorm.DB, count int) error
+}
+
+type SeedOptions struct {
+	Only        []string // run only these seeders, their dependencies are expected to be seeded already
+	Except      []string
+	Count       int  // records each seeder creates, 1 by default
+	BatchSize   int  // records saved per INSERT by factories, factory.DefaultBatchSize by default
+	Transaction bool // run every seeder in one transaction, rolled back on failure
+	// Progress is called as factories save records, saved of the total the factory calls of the seeder
+	// announced so far, the total grows with each call
+	Progress func(seeder string, saved, total int)
+}
+
+// SeedResult is the outcome of one seeder, rows counts the records inserted through GORM.
+type SeedResult struct {
+	Seeder   string
+	Rows     int64
+	Duration time.Duration
+}
+
+// Seed runs the selected seeders, dependencies first, and stops at the first failure.
+// The results of the seeders that ran are returned in both cases, rows included even when rolled back.
+func (r *DatabaseRegistry) Seed(db *gorm.DB, opts SeedOptions) ([]SeedResult, error) {
+	if opts.Count <= 0 {
+		opts.Count = 1
+	}
+
+	seeders, err := r.seedOrder(opts.Only, opts.Except)
+	if err != nil {
+		return nil, err
+	}
+	if len(seeders) == 0 {
+		return nil, fmt.Errorf("no seeders to run, register them in Database.Seeders")
+	}
+
+	if err := registerSeedCounter(db); err != nil {
+		return nil, err
+	}
+
+	if opts.BatchSize > 0 {
+		db = db.Session(&gorm.Session{CreateBatchSize: opts.BatchSize})
+	}
+
+	results := make([]SeedResult, 0, len(seeders))
+	if !opts.Transaction {
+		err := runSeeders(db, seeders, opts, &results)
+		return results, err
+	}
+
+	err = db.Transaction(func(tx *gorm.DB) error {
+		return runSeeders(tx, seeders, opts, &results)
+	})
+	if err != nil {
+		return results, fmt.Errorf("%v, every seeder was rolled back", err)
+	}
+	return results, nil
+}
+
+// helper
+
+func runSeeders(db *gorm.DB, seeders []Seeder, opts SeedOptions, results *[]SeedResult) error {
+	for _, s := range seeders {
+		// the progress shows the seeder instead, logging would break its line
+		if opts.Progress == nil {
+			slog.Info("Seeding", slog.String("seeder", s.Name), slog.Int("count", opts.Count))
+		}
+
+		var rows atomic.Int64
+		start := time.Now()
+		ctx := context.WithValue(db.Statement.Context, seedRowsKey{}, &rows)
+
+		if opts.Progress != nil {
+			saved, total := 0, 0
+			ctx = factory.WithProgress(ctx, func(n, added int) {
+				saved += n
+				total += added
+				opts.Progress(s.Name, saved, total)
+			})
+		}
+
+		err := s.Run(db.WithContext(ctx), opts.Count)
+		*results = append(*results, SeedResult{Seeder: s.Name, Rows: rows.Load(), Duration: time.Since(start)})
+		if err != nil {
+			return fmt.Errorf("seeder %s failed: %v", s.Name, err)
+		}
+	}
+	return nil
+}
+
+type seedRowsKey struct{}
+
+// registerSeedCounter counts the rows created by a seeder into the counter carried by its context.
+func registerSeedCounter(db *gorm.DB) error {
+	if db.Callback().Create().Get("seed:rows") != nil {
+		return nil
+	}
+
+	return db.Callback().Create().After("gorm:create").Register("seed:rows", func(tx *gorm.DB) {
+		if tx.Error != nil || tx.Statement.Context == nil {
+			return
+		}
+		if rows, ok := tx.Statement.Context.Value(seedRowsKey{}).(*atomic.Int64); ok {
+			rows.Add(tx.Statement.RowsAffected)
+		}
+	})
+}
+
+// seedOrder selects the seeders and sorts them so dependencies run first, keeping the registration order otherwise.
+func (r *DatabaseRegistry) seedOrder(only, except []string) ([]Seeder, error) {
+	index := make(map[string]int, len(r.Seeders))
+	for i, s := range r.Seeders {
+		if s.Name == "" || s.Run == nil {
+			return nil, fmt.Errorf("seeder #%d needs a name and a Run function", i+1)
+		}
+		if _, ok := index[s.Name]; ok {
+			return nil, fmt.Errorf("duplicate seeder %s", s.Name)
+		}
+		index[s.Name] = i
+	}
+
+	for _, name := range slices.Concat(only, except) {
+		if _, ok := index[name]; !ok {
+			return nil, fmt.Errorf("unknown seeder %s", name)
+		}
+	}
+	for _, s := range r.Seeders {
+		for _, dep := range s.Depends {
+			if _, ok := index[dep]; !ok {
+				return nil, fmt.Errorf("seeder %s depends on unknown seeder %s", s.Name, dep)
+			}
+		}
+	}
+
+	// depth first, a seeder is appended once all of its dependencies are
+	const (
+		visiting = 1
+		done     = 2
+	)
+	state := make(map[string]int, len(r.Seeders))
+	var ordered []Seeder
+
+	var visit func(s Seeder, path []string) error
+	visit = func(s Seeder, path []string) error {
+		switch state[s.Name] {
+		case done:
+			return nil
+		case visiting:
+			return fmt.Errorf("seeders depend on each other: %s", strings.Join(append(path, s.Name), " -> "))
+		}
+
+		state[s.Name] = visiting
+		for _, dep := range s.Depends {
+			if err := visit(r.Seeders[index[dep]], append(path, s.Name)); err != nil {
+				return err
+			}
+		}
+		state[s.Name] = done
+		ordered = append(ordered, s)
+		return nil
+	}
+
+	for _, s := range r.Seeders {
+		if err := visit(s, nil); err != nil {
+			return nil, err
+		}
+	}
+
+	return slices.DeleteFunc(ordered, func(s Seeder) bool {
+		return (len(only) > 0 && !slices.Contains(only, s.Name)) || slices.Contains(except, s.Name)
+	}), nil
+}
@@ -0,0 +1,151 @@
+package structers
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"gorm.io/gorm/utils/tests"
+	"webservices/packages/factory"
+)
+
+func TestSeedOrder(t *testing.T) {
+	run := func(*gorm.DB, int) error { return nil }
+	seeders := func(deps ...[]string) []Seeder {
+		var result []Seeder
+		for _, d := range deps {
+			result = append(result, Seeder{Name: d[0], Depends: d[1:], Run: run})
+		}
+		return result
+	}
+
+	tests := []struct {
+		name    string
+		seeders []Seeder
+		only    []string
+		except  []string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:    "registration order",
+			seeders: seeders([]string{"users"}, []string{"products"}),
+			want:    []string{"users", "products"},
+		},
+		{
+			name:    "dependencies first",
+			seeders: seeders([]string{"orders", "users", "products"}, []string{"products"}, []string{"users"}),
+			want:    []string{"users", "products", "orders"},
+		},
+		{
+			name:    "transitive dependencies",
+			seeders: seeders([]string{"items", "orders"}, []string{"orders", "users"}, []string{"users"}),
+			want:    []string{"users", "orders", "items"},
+		},
+		{
+			name:    "only keeps the selection without its dependencies",
+			seeders: seeders([]string{"orders", "users"}, []string{"users"}, []string{"products"}),
+			only:    []string{"orders"},
+			want:    []string{"orders"},
+		},
+		{
+			name:    "except",
+			seeders: seeders([]string{"orders", "users"}, []string{"users"}, []string{"products"}),
+			except:  []string{"products"},
+			want:    []string{"users", "orders"},
+		},
+		{
+			name:    "cycle",
+			seeders: seeders([]string{"a", "b"}, []string{"b", "c"}, []string{"c", "a"}),
+			wantErr: "a -> b -> c -> a",
+		},
+		{
+			name:    "unknown dependency",
+			seeders: seeders([]string{"orders", "users"}),
+			wantErr: "depends on unknown seeder users",
+		},
+		{
+			name:    "unknown only",
+			seeders: seeders([]string{"users"}),
+			only:    []string{"orders"},
+			wantErr: "unknown seeder orders",
+		},
+		{
+			name:    "duplicate",
+			seeders: seeders([]string{"users"}, []string{"users"}),
+			wantErr: "duplicate seeder users",
+		},
+		{
+			name:    "missing run",
+			seeders: []Seeder{{Name: "users"}},
+			wantErr: "needs a name and a Run function",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := &DatabaseRegistry{Seeders: tt.seeders}
+			ordered, err := registry.seedOrder(tt.only, tt.except)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var got []string
+			for _, s := range ordered {
+				got = append(got, s.Name)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type seeded struct {
+	ID   uint
+	Name string
+}
+
+func TestSeedProgress(t *testing.T) {
+	db, err := gorm.Open(tests.DummyDialector{}, &gorm.Config{DryRun: true, Logger: logger.Discard})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every factory call adds to the total of its seeder
+	run := func(db *gorm.DB, count int) error {
+		records := factory.Define(db, func(seq int) seeded { return seeded{Name: fmt.Sprint(seq)} })
+		if _, err := records.CreateMany(count); err != nil {
+			return err
+		}
+		return records.CreateBatch(2)
+	}
+	registry := &DatabaseRegistry{Seeders: []Seeder{{Name: "users", Run: run}, {Name: "orders", Run: run}}}
+
+	var calls []string
+	_, err = registry.Seed(db, SeedOptions{Count: 3, BatchSize: 2, Progress: func(seeder string, saved, total int) {
+		calls = append(calls, fmt.Sprintf("%s %d/%d", seeder, saved, total))
+	}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var want []string
+	for _, seeder := range []string{"users", "orders"} {
+		want = append(want,
+			seeder+" 0/3", seeder+" 2/3", seeder+" 3/3",
+			seeder+" 3/5", seeder+" 5/5")
+	}
+	if !reflect.DeepEqual(calls, want) {
+		t.Errorf("progress = %v, want %v", calls, want)
+	}
+}
@@ -0,0 +1,54 @@
+package structers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"golang.org/x/sync/errgroup"
+	"gorm.io/gorm"
+)
+
+// exportSnapshot calls fn for each of the n tables on up to concurrency workers. Every worker reads
+// through its own REPEATABLE READ transaction importing the snapshot exported by a coordinating
+// transaction, so all tables are dumped as of the same point in time. The first error cancels the rest.
+func exportSnapshot(db *gorm.DB, n, concurrency int, fn func(tx *gorm.DB, i int) error) error {
+	readOnly := &sql.TxOptions{Isolation: sql.LevelRepeatableRead, ReadOnly: true}
+
+	// the snapshot stays importable as long as the coordinator is open
+	coordinator := db.Begin(readOnly)
+	if coordinator.Error != nil {
+		return fmt.Errorf("failed to begin snapshot: %v", coordinator.Error)
+	}
+	defer coordinator.Rollback()
+
+	var snapshot string
+	if err := coordinator.Raw("SELECT pg_export_snapshot()").Scan(&snapshot).Error; err != nil {
+		return fmt.Errorf("failed to export snapshot: %v", err)
+	}
+
+	g, ctx := errgroup.WithContext(context.Background())
+	g.SetLimit(concurrency)
+
+	for i := 0; i < n; i++ {
+		g.Go(func() error {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			tx := db.WithContext(ctx).Begin(readOnly)
+			if tx.Error != nil {
+				return tx.Error
+			}
+			defer tx.Rollback()
+
+			if err := tx.Exec("SET TRANSACTION SNAPSHOT " + quoteLiteral(snapshot)).Error; err != nil {
+				return fmt.Errorf("failed to import snapshot: %v", err)
+			}
+
+			return fn(tx, i)
+		})
+	}
+
+	return g.Wait()
+}
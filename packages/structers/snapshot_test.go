@@ -0,0 +1,128 @@
+package structers
+
+import (
+	"errors"
+	"os"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+func TestExportSnapshot(t *testing.T) {
+	db := testDB(t)
+
+	if err := db.Exec("CREATE TABLE events (id serial PRIMARY KEY); INSERT INTO events DEFAULT VALUES").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	const n, concurrency = 6, 3
+	var running, peak atomic.Int32
+	var mu sync.Mutex
+	counts := make(map[int]int64)
+
+	err := exportSnapshot(db, n, concurrency, func(tx *gorm.DB, i int) error {
+		now := running.Add(1)
+		defer running.Add(-1)
+		for {
+			if highest := peak.Load(); now <= highest || peak.CompareAndSwap(highest, now) {
+				break
+			}
+		}
+
+		var isolation string
+		if err := tx.Raw("SHOW transaction_isolation").Scan(&isolation).Error; err != nil {
+			return err
+		}
+		if isolation != "repeatable read" {
+			t.Errorf("worker %d isolation = %s, want repeatable read", i, isolation)
+		}
+
+		// a row committed during the export is seen by none of the workers
+		if i == 0 {
+			if err := db.Exec("INSERT INTO events DEFAULT VALUES").Error; err != nil {
+				return err
+			}
+		}
+		time.Sleep(50 * time.Millisecond)
+
+		var count int64
+		if err := tx.Table("events").Count(&count).Error; err != nil {
+			return err
+		}
+		mu.Lock()
+		counts[i] = count
+		mu.Unlock()
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(counts) != n {
+		t.Fatalf("%d tables exported, want %d", len(counts), n)
+	}
+	for i, count := range counts {
+		if count != 1 {
+			t.Errorf("worker %d counted %d events, want the 1 of the snapshot", i, count)
+		}
+	}
+	if p := peak.Load(); p < 2 || p > concurrency {
+		t.Errorf("%d workers ran at once, want between 2 and %d", p, concurrency)
+	}
+}
+
+func TestExportSnapshotCancelsOnError(t *testing.T) {
+	db := testDB(t)
+
+	failure := errors.New("export failed")
+	var calls atomic.Int32
+
+	err := exportSnapshot(db, 20, 2, func(tx *gorm.DB, i int) error {
+		calls.Add(1)
+		if i == 0 {
+			return failure
+		}
+		time.Sleep(20 * time.Millisecond)
+		return nil
+	})
+	if !errors.Is(err, failure) {
+		t.Fatalf("error = %v, want %v", err, failure)
+	}
+	if c := calls.Load(); c >= 20 {
+		t.Errorf("%d tables exported after the failure, want the rest cancelled", c)
+	}
+}
+
+func TestConcurrentBackupRemovesPartialFiles(t *testing.T) {
+	db := testDB(t)
+
+	err := db.Exec(`CREATE TABLE users (id serial PRIMARY KEY);
+		CREATE TABLE orders (id serial PRIMARY KEY);
+		CREATE TABLE items (id serial PRIMARY KEY);
+		INSERT INTO users DEFAULT VALUES; INSERT INTO items DEFAULT VALUES`).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	output := t.TempDir()
+	registry := &DatabaseRegistry{Tables: []string{"users", "orders", "items"}}
+	err = registry.Backup(db, BackupOptions{
+		Output:      output,
+		Concurrency: 3,
+		Where:       map[string]string{"orders": "missing_column > 0"},
+	})
+	if err == nil {
+		t.Fatal("backup with an invalid filter succeeded")
+	}
+
+	entries, err := os.ReadDir(output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, entry := range entries {
+		t.Errorf("failed backup left %s behind", entry.Name())
+	}
+}
@@ -2,14 +2,14 @@ package registry
 
 import (
 	"webservices/packages/structers"
-
-	"gorm.io/gorm"
 )
 
 var Database = &structers.DatabaseRegistry{
 	Enums: []structers.Enum{
 		// example:
 		// {Name: "gendre", Values: []string{"male", "female"}},
+		// rename values on the next migrate, values no longer listed are removed:
+		// {Name: "gendre", Values: []string{"man", "woman"}, Renames: map[string]string{"male": "man", "female": "woman"}},
 	},
 	Tables: []string{
 		// tables for backup
@@ -18,14 +18,33 @@ var Database = &structers.DatabaseRegistry{
 		// models for auto-migrate, example:
 		// model.User{},
 	},
+	// directory of versioned migrations, create with `make:migration`
+	Migrations: "./database/migrations",
 	Extensions: []string{
 		// example:
 		// "uuid-ossp",
 	},
-	Factories: []func(*gorm.DB) error{
-		// register factory for seeding, example:
-		// func(db *gorm.DB) error {
-		// 	return factory.NewUserFactory(db).CreateBatch(1)
+	BackupFormats: map[string]structers.BackupFormat{
+		// custom formats for `db:backup --format=<name>`, example:
+		// "xml": {Extension: "xml", NewWriter: func(w io.Writer, opts structers.BackupOptions) structers.BackupWriter { ... }},
+	},
+	BackupWhere: map[string]string{
+		// filter the rows `db:backup` exports per table, example:
+		// "audit_logs": "created_at > now() - interval '30 days'",
+	},
+	Masks: map[string]map[string]structers.Mask{
+		// columns masked by `db:backup --profile=anonymized`, example:
+		// "users": {
+		// 	"email": {Type: structers.MaskEmail},
+		// 	"name":  {Type: structers.MaskFake, Fake: "name"},
+		// 	"phone": {Type: structers.MaskNull},
 		// },
 	},
+	Seeders: []structers.Seeder{
+		// register seeders for `db:seed`, Run receives --count, example:
+		// {Name: "users", Run: func(db *gorm.DB, count int) error {
+		// 	return factory.NewUserFactory(db).CreateBatch(count)
+		// }},
+		// {Name: "orders", Depends: []string{"users"}, Run: func(db *gorm.DB, count int) error { ... }},
+	},
 }
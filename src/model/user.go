@@ -0,0 +1,18 @@
+package model
+
+import (
+	"time"
+)
+
+type User struct {
+	ID        string    `gorm:"primaryKey;type:uuid;default:uuid_generate_v4()" json:"id"`
+	Name      string    `gorm:"column:name" faker:"name" json:"name"`
+	Email     string    `gorm:"column:email;uniqueIndex" faker:"email" json:"email"`
+	Role      string    `gorm:"column:role;default:member" json:"role"`
+	CreatedAt time.Time `gorm:"column:created_at;default:now();<-:create" json:"createdAt"`
+	UpdatedAt time.Time `gorm:"column:updated_at;autoUpdateTime" json:"updatedAt"`
+}
+
+func (User) TableName() string {
+	return "users"
+}